@@ -0,0 +1,191 @@
+// Package buildconfig constructs build config apply configurations for a given build strategy
+// and validates them before they are applied.
+package buildconfig
+
+import (
+	buildv1 "github.com/openshift/api/build/v1"
+	applybuildv1 "github.com/openshift/client-go/build/applyconfigurations/build/v1"
+	buildwebhook "github.com/openshift/client-go/build/webhook"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Builder assembles a BuildConfigApplyConfiguration.
+type Builder struct {
+	config *applybuildv1.BuildConfigApplyConfiguration
+}
+
+// Docker returns a Builder for a build config using the Docker strategy.
+func Docker(name, namespace string) *Builder {
+	return newBuilder(name, namespace, applybuildv1.BuildStrategy().
+		WithType(buildv1.DockerBuildStrategyType).
+		WithDockerStrategy(applybuildv1.DockerBuildStrategy()))
+}
+
+// Source returns a Builder for a build config using the Source strategy with the given builder
+// image, an ImageStreamTag, ImageStreamImage or DockerImage reference.
+func Source(name, namespace string, builderImage corev1.ObjectReference) *Builder {
+	return newBuilder(name, namespace, applybuildv1.BuildStrategy().
+		WithType(buildv1.SourceBuildStrategyType).
+		WithSourceStrategy(applybuildv1.SourceBuildStrategy().WithFrom(builderImage)))
+}
+
+// Custom returns a Builder for a build config using the Custom strategy with the given builder
+// image, an ImageStreamTag, ImageStreamImage or DockerImage reference.
+func Custom(name, namespace string, builderImage corev1.ObjectReference) *Builder {
+	return newBuilder(name, namespace, applybuildv1.BuildStrategy().
+		WithType(buildv1.CustomBuildStrategyType).
+		WithCustomStrategy(applybuildv1.CustomBuildStrategy().WithFrom(builderImage)))
+}
+
+// JenkinsPipeline returns a Builder for a build config using the JenkinsPipeline strategy.
+func JenkinsPipeline(name, namespace string) *Builder {
+	return newBuilder(name, namespace, applybuildv1.BuildStrategy().
+		WithType(buildv1.JenkinsPipelineBuildStrategyType).
+		WithJenkinsPipelineStrategy(applybuildv1.JenkinsPipelineBuildStrategy()))
+}
+
+func newBuilder(name, namespace string, strategy *applybuildv1.BuildStrategyApplyConfiguration) *Builder {
+	return &Builder{
+		config: applybuildv1.BuildConfig(name, namespace).
+			WithSpec(applybuildv1.BuildConfigSpec().WithStrategy(strategy)),
+	}
+}
+
+// WithGitSource builds the given git repository at ref, the default branch if ref is empty.
+func (b *Builder) WithGitSource(uri, ref string) *Builder {
+	git := applybuildv1.GitBuildSource().WithURI(uri)
+	if len(ref) > 0 {
+		git.WithRef(ref)
+	}
+	b.source().WithType(buildv1.BuildSourceGit).WithGit(git)
+	return b
+}
+
+// WithBinarySource builds content uploaded when the build is started. If asFile is not empty the
+// upload is a single file placed under that name, otherwise an archive that is extracted.
+func (b *Builder) WithBinarySource(asFile string) *Builder {
+	binary := applybuildv1.BinaryBuildSource()
+	if len(asFile) > 0 {
+		binary.WithAsFile(asFile)
+	}
+	b.source().WithType(buildv1.BuildSourceBinary).WithBinary(binary)
+	return b
+}
+
+// WithDockerfile builds the given Dockerfile contents.
+func (b *Builder) WithDockerfile(dockerfile string) *Builder {
+	source := b.source().WithDockerfile(dockerfile)
+	if source.Type == nil {
+		source.WithType(buildv1.BuildSourceDockerfile)
+	}
+	return b
+}
+
+// WithContextDir builds the given sub directory of the source.
+func (b *Builder) WithContextDir(contextDir string) *Builder {
+	b.source().WithContextDir(contextDir)
+	return b
+}
+
+// WithJenkinsfile sets the pipeline of a JenkinsPipeline build config, either its contents or,
+// with a git source, its path in the repository.
+func (b *Builder) WithJenkinsfile(jenkinsfile, jenkinsfilePath string) *Builder {
+	strategy := b.config.Spec.Strategy.JenkinsPipelineStrategy
+	if strategy == nil {
+		strategy = applybuildv1.JenkinsPipelineBuildStrategy()
+		b.config.Spec.Strategy.WithJenkinsPipelineStrategy(strategy)
+	}
+	if len(jenkinsfile) > 0 {
+		strategy.WithJenkinsfile(jenkinsfile)
+	}
+	if len(jenkinsfilePath) > 0 {
+		strategy.WithJenkinsfilePath(jenkinsfilePath)
+	}
+	return b
+}
+
+// WithOutputImageStreamTag pushes the built image to the given "name:tag" image stream tag in the
+// build config namespace.
+func (b *Builder) WithOutputImageStreamTag(name string) *Builder {
+	b.config.Spec.WithOutput(applybuildv1.BuildOutput().WithTo(corev1.ObjectReference{Kind: "ImageStreamTag", Name: name}))
+	return b
+}
+
+// WithOutputDockerImage pushes the built image to the given pull spec.
+func (b *Builder) WithOutputDockerImage(pullSpec string) *Builder {
+	b.config.Spec.WithOutput(applybuildv1.BuildOutput().WithTo(corev1.ObjectReference{Kind: "DockerImage", Name: pullSpec}))
+	return b
+}
+
+// WithConfigChangeTrigger starts a build when the build config is created.
+func (b *Builder) WithConfigChangeTrigger() *Builder {
+	b.config.Spec.WithTriggers(applybuildv1.BuildTriggerPolicy().WithType(buildv1.ConfigChangeBuildTriggerType))
+	return b
+}
+
+// WithImageChangeTrigger starts a build when the given image stream tag changes, or the builder
+// image of the strategy when from is nil.
+func (b *Builder) WithImageChangeTrigger(from *corev1.ObjectReference) *Builder {
+	trigger := applybuildv1.ImageChangeTrigger()
+	if from != nil {
+		trigger.WithFrom(*from)
+	}
+	b.config.Spec.WithTriggers(applybuildv1.BuildTriggerPolicy().
+		WithType(buildv1.ImageChangeBuildTriggerType).
+		WithImageChange(trigger))
+	return b
+}
+
+// WithWebHookTrigger starts a build when the webhook of the given type, Generic, GitHub, GitLab or
+// Bitbucket, is invoked with the value of the WebHookSecretKey key of the named secret. The
+// deprecated generic and github spellings are accepted and stored in their current form.
+func (b *Builder) WithWebHookTrigger(triggerType buildv1.BuildTriggerType, secretName string) *Builder {
+	triggerType = buildwebhook.NormalizeTriggerType(triggerType)
+	webhook := applybuildv1.WebHookTrigger().WithSecretReference(applybuildv1.SecretLocalReference().WithName(secretName))
+	trigger := applybuildv1.BuildTriggerPolicy().WithType(triggerType)
+	switch triggerType {
+	case buildv1.GenericWebHookBuildTriggerType:
+		trigger.WithGenericWebHook(webhook)
+	case buildv1.GitHubWebHookBuildTriggerType:
+		trigger.WithGitHubWebHook(webhook)
+	case buildv1.GitLabWebHookBuildTriggerType:
+		trigger.WithGitLabWebHook(webhook)
+	case buildv1.BitbucketWebHookBuildTriggerType:
+		trigger.WithBitbucketWebHook(webhook)
+	}
+	b.config.Spec.WithTriggers(trigger)
+	return b
+}
+
+// WithRunPolicy sets how builds of the build config are scheduled.
+func (b *Builder) WithRunPolicy(runPolicy buildv1.BuildRunPolicy) *Builder {
+	b.config.Spec.WithRunPolicy(runPolicy)
+	return b
+}
+
+// WithHistoryLimits sets the number of successful and failed builds kept.
+func (b *Builder) WithHistoryLimits(successful, failed int32) *Builder {
+	b.config.Spec.WithSuccessfulBuildsHistoryLimit(successful).WithFailedBuildsHistoryLimit(failed)
+	return b
+}
+
+// ApplyConfiguration returns the assembled apply configuration without validating it, so that
+// fields the Builder does not cover can be set on it.
+func (b *Builder) ApplyConfiguration() *applybuildv1.BuildConfigApplyConfiguration {
+	return b.config
+}
+
+// Build validates and returns the assembled apply configuration.
+func (b *Builder) Build() (*applybuildv1.BuildConfigApplyConfiguration, error) {
+	if errs := Validate(b.config); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+	return b.config, nil
+}
+
+func (b *Builder) source() *applybuildv1.BuildSourceApplyConfiguration {
+	if b.config.Spec.Source == nil {
+		b.config.Spec.WithSource(applybuildv1.BuildSource())
+	}
+	return b.config.Spec.Source
+}
@@ -0,0 +1,332 @@
+package buildconfig
+
+import (
+	fmt "fmt"
+	url "net/url"
+	path "path"
+	strings "strings"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	applybuildv1 "github.com/openshift/client-go/build/applyconfigurations/build/v1"
+	buildwebhook "github.com/openshift/client-go/build/webhook"
+	corev1 "k8s.io/api/core/v1"
+	field "k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate checks config against the rules the API server enforces for build configs, so that
+// invalid configurations are reported before they are applied.
+func Validate(config *applybuildv1.BuildConfigApplyConfiguration) field.ErrorList {
+	var errs field.ErrorList
+	if config.GetName() == nil || len(*config.GetName()) == 0 {
+		errs = append(errs, field.Required(field.NewPath("metadata", "name"), ""))
+	}
+	specPath := field.NewPath("spec")
+	spec := config.Spec
+	if spec == nil {
+		return append(errs, field.Required(specPath, ""))
+	}
+
+	strategyType, strategyErrs := validateStrategy(spec.Strategy, specPath.Child("strategy"))
+	errs = append(errs, strategyErrs...)
+	errs = append(errs, validateSource(spec.Source, strategyType, specPath.Child("source"))...)
+	if strategyType == buildv1.JenkinsPipelineBuildStrategyType {
+		errs = append(errs, validateJenkinsPipeline(spec, specPath)...)
+	}
+	if spec.Output != nil && spec.Output.To != nil {
+		errs = append(errs, validateOutputTo(spec.Output.To, specPath.Child("output", "to"))...)
+	}
+	errs = append(errs, validateTriggers(spec, specPath.Child("triggers"))...)
+
+	if spec.RunPolicy != nil {
+		switch *spec.RunPolicy {
+		case buildv1.BuildRunPolicyParallel, buildv1.BuildRunPolicySerial, buildv1.BuildRunPolicySerialLatestOnly:
+		default:
+			errs = append(errs, field.NotSupported(specPath.Child("runPolicy"), *spec.RunPolicy, []string{
+				string(buildv1.BuildRunPolicyParallel), string(buildv1.BuildRunPolicySerial), string(buildv1.BuildRunPolicySerialLatestOnly),
+			}))
+		}
+	}
+	if spec.CompletionDeadlineSeconds != nil && *spec.CompletionDeadlineSeconds <= 0 {
+		errs = append(errs, field.Invalid(specPath.Child("completionDeadlineSeconds"), *spec.CompletionDeadlineSeconds, "must be greater than zero"))
+	}
+	if spec.SuccessfulBuildsHistoryLimit != nil && *spec.SuccessfulBuildsHistoryLimit < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("successfulBuildsHistoryLimit"), *spec.SuccessfulBuildsHistoryLimit, "must be greater than or equal to zero"))
+	}
+	if spec.FailedBuildsHistoryLimit != nil && *spec.FailedBuildsHistoryLimit < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("failedBuildsHistoryLimit"), *spec.FailedBuildsHistoryLimit, "must be greater than or equal to zero"))
+	}
+	return errs
+}
+
+// validateStrategy checks that exactly one strategy is set and returns its type.
+func validateStrategy(strategy *applybuildv1.BuildStrategyApplyConfiguration, fldPath *field.Path) (buildv1.BuildStrategyType, field.ErrorList) {
+	if strategy == nil {
+		return "", field.ErrorList{field.Required(fldPath, "")}
+	}
+
+	var errs field.ErrorList
+	var set []buildv1.BuildStrategyType
+	if strategy.DockerStrategy != nil {
+		set = append(set, buildv1.DockerBuildStrategyType)
+	}
+	if strategy.SourceStrategy != nil {
+		set = append(set, buildv1.SourceBuildStrategyType)
+		errs = append(errs, validateFrom(strategy.SourceStrategy.From, fldPath.Child("sourceStrategy", "from"))...)
+	}
+	if strategy.CustomStrategy != nil {
+		set = append(set, buildv1.CustomBuildStrategyType)
+		errs = append(errs, validateFrom(strategy.CustomStrategy.From, fldPath.Child("customStrategy", "from"))...)
+	}
+	if strategy.JenkinsPipelineStrategy != nil {
+		set = append(set, buildv1.JenkinsPipelineBuildStrategyType)
+	}
+	if strategy.DockerStrategy != nil && strategy.DockerStrategy.From != nil {
+		errs = append(errs, validateFrom(strategy.DockerStrategy.From, fldPath.Child("dockerStrategy", "from"))...)
+	}
+	if strategy.DockerStrategy != nil && strategy.DockerStrategy.DockerfilePath != nil {
+		if p := *strategy.DockerStrategy.DockerfilePath; path.IsAbs(p) || escapes(p) {
+			errs = append(errs, field.Invalid(fldPath.Child("dockerStrategy", "dockerfilePath"), p, "must be a relative path within the context directory"))
+		}
+	}
+
+	switch len(set) {
+	case 0:
+		return "", append(errs, field.Required(fldPath, "must provide a value for exactly one of sourceStrategy, customStrategy, dockerStrategy, or jenkinsPipelineStrategy"))
+	case 1:
+	default:
+		return "", append(errs, field.Invalid(fldPath, set, "must provide a value for exactly one of sourceStrategy, customStrategy, dockerStrategy, or jenkinsPipelineStrategy"))
+	}
+	if strategy.Type != nil && *strategy.Type != set[0] {
+		errs = append(errs, field.Invalid(fldPath.Child("type"), *strategy.Type, fmt.Sprintf("must match the configured strategy %s", set[0])))
+	}
+	return set[0], errs
+}
+
+// validateFrom checks a builder image reference.
+func validateFrom(from *corev1.ObjectReference, fldPath *field.Path) field.ErrorList {
+	if from == nil {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	var errs field.ErrorList
+	switch from.Kind {
+	case "ImageStreamTag":
+		errs = append(errs, validateImageStreamTagName(from.Name, fldPath.Child("name"))...)
+	case "ImageStreamImage":
+		if parts := strings.Split(from.Name, "@"); len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("name"), from.Name, "must be of the form <stream_name>@<id>"))
+		}
+	case "DockerImage":
+		if len(from.Name) == 0 {
+			errs = append(errs, field.Required(fldPath.Child("name"), ""))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("kind"), from.Kind, []string{"ImageStreamTag", "ImageStreamImage", "DockerImage"}))
+	}
+	return errs
+}
+
+// validateOutputTo checks the image a build pushes to.
+func validateOutputTo(to *corev1.ObjectReference, fldPath *field.Path) field.ErrorList {
+	switch to.Kind {
+	case "ImageStreamTag":
+		return validateImageStreamTagName(to.Name, fldPath.Child("name"))
+	case "DockerImage":
+		if len(to.Name) == 0 {
+			return field.ErrorList{field.Required(fldPath.Child("name"), "")}
+		}
+		if len(to.Namespace) > 0 {
+			return field.ErrorList{field.Invalid(fldPath.Child("namespace"), to.Namespace, "namespace is not valid when used with a 'DockerImage'")}
+		}
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(fldPath.Child("kind"), to.Kind, []string{"ImageStreamTag", "DockerImage"})}
+}
+
+func validateImageStreamTagName(name string, fldPath *field.Path) field.ErrorList {
+	if parts := strings.Split(name, ":"); len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return field.ErrorList{field.Invalid(fldPath, name, "ImageStreamTag object references must be in the form <name>:<tag>")}
+	}
+	return nil
+}
+
+// validateSource checks that the source types are consistent with each other and the strategy.
+func validateSource(source *applybuildv1.BuildSourceApplyConfiguration, strategyType buildv1.BuildStrategyType, fldPath *field.Path) field.ErrorList {
+	if source == nil {
+		if strategyType == buildv1.DockerBuildStrategyType || strategyType == buildv1.SourceBuildStrategyType {
+			return field.ErrorList{field.Required(fldPath, fmt.Sprintf("must provide a source for the %s strategy", strategyType))}
+		}
+		return nil
+	}
+
+	var errs field.ErrorList
+	if source.Binary != nil && source.Git != nil {
+		errs = append(errs, field.Forbidden(fldPath.Child("git"), "may not be set when binary is also set"))
+	}
+	if source.Binary != nil && source.Binary.AsFile != nil {
+		asFile := *source.Binary.AsFile
+		if len(asFile) == 0 || asFile == "." || asFile == ".." || strings.Contains(asFile, "/") || strings.Contains(asFile, `\`) {
+			errs = append(errs, field.Invalid(fldPath.Child("binary", "asFile"), asFile, "file name may not contain slashes or be '.' or '..'"))
+		}
+	}
+	if source.Git != nil {
+		errs = append(errs, validateGitURI(source.Git.URI, fldPath.Child("git", "uri"))...)
+	}
+	if source.Dockerfile != nil && strategyType != buildv1.DockerBuildStrategyType {
+		errs = append(errs, field.Invalid(fldPath.Child("dockerfile"), "", "may only be set for the Docker strategy"))
+	}
+	if source.ContextDir != nil && (path.IsAbs(*source.ContextDir) || escapes(*source.ContextDir)) {
+		errs = append(errs, field.Invalid(fldPath.Child("contextDir"), *source.ContextDir, "context dir must be a relative path within the source"))
+	}
+	for i, image := range source.Images {
+		errs = append(errs, validateFrom(image.From, fldPath.Child("images").Index(i).Child("from"))...)
+	}
+
+	if source.Type != nil {
+		var present bool
+		switch *source.Type {
+		case buildv1.BuildSourceGit:
+			present = source.Git != nil
+		case buildv1.BuildSourceBinary:
+			present = source.Binary != nil
+		case buildv1.BuildSourceDockerfile:
+			present = source.Dockerfile != nil
+		case buildv1.BuildSourceImage:
+			present = len(source.Images) > 0
+		case buildv1.BuildSourceNone:
+			present = true
+		}
+		if !present {
+			errs = append(errs, field.Invalid(fldPath.Child("type"), *source.Type, "source type does not match the provided source"))
+		}
+	}
+	if (strategyType == buildv1.DockerBuildStrategyType || strategyType == buildv1.SourceBuildStrategyType) &&
+		source.Git == nil && source.Binary == nil && source.Dockerfile == nil && len(source.Images) == 0 {
+		errs = append(errs, field.Required(fldPath, "must provide a value for at least one of git, binary, dockerfile, or images"))
+	}
+	return errs
+}
+
+func validateGitURI(uri *string, fldPath *field.Path) field.ErrorList {
+	if uri == nil || len(*uri) == 0 {
+		return field.ErrorList{field.Required(fldPath, "")}
+	}
+	// scp-like syntax such as git@github.com:org/repo.git
+	if at, colon := strings.Index(*uri, "@"), strings.Index(*uri, ":"); at > 0 && colon > at && !strings.Contains(*uri, "://") {
+		return nil
+	}
+	u, err := url.Parse(*uri)
+	if err != nil || len(u.Scheme) == 0 || (u.Scheme != "file" && len(u.Host) == 0) {
+		return field.ErrorList{field.Invalid(fldPath, *uri, "uri is not a valid git repository URL")}
+	}
+	return nil
+}
+
+func validateJenkinsPipeline(spec *applybuildv1.BuildConfigSpecApplyConfiguration, fldPath *field.Path) field.ErrorList {
+	strategy := spec.Strategy.JenkinsPipelineStrategy
+	strategyPath := fldPath.Child("strategy", "jenkinsPipelineStrategy")
+	var errs field.ErrorList
+	if strategy.Jenkinsfile != nil && strategy.JenkinsfilePath != nil {
+		errs = append(errs, field.Invalid(strategyPath.Child("jenkinsfilePath"), *strategy.JenkinsfilePath, "only one of jenkinsfile or jenkinsfilePath may be set"))
+	}
+	if strategy.Jenkinsfile == nil && (spec.Source == nil || spec.Source.Git == nil) {
+		errs = append(errs, field.Required(strategyPath.Child("jenkinsfile"), "must provide a value for jenkinsfile or a git source"))
+	}
+	if strategy.JenkinsfilePath != nil && (path.IsAbs(*strategy.JenkinsfilePath) || escapes(*strategy.JenkinsfilePath)) {
+		errs = append(errs, field.Invalid(strategyPath.Child("jenkinsfilePath"), *strategy.JenkinsfilePath, "must be a relative path within the context directory"))
+	}
+	return errs
+}
+
+// validateTriggers checks that every trigger carries exactly the parameters of its type.
+func validateTriggers(spec *applybuildv1.BuildConfigSpecApplyConfiguration, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	emptyImageChange := 0
+	for i, trigger := range spec.Triggers {
+		triggerPath := fldPath.Index(i)
+		if trigger.Type == nil {
+			errs = append(errs, field.Required(triggerPath.Child("type"), ""))
+			continue
+		}
+
+		set := 0
+		for _, present := range []bool{trigger.GenericWebHook != nil, trigger.GitHubWebHook != nil, trigger.GitLabWebHook != nil, trigger.BitbucketWebHook != nil, trigger.ImageChange != nil} {
+			if present {
+				set++
+			}
+		}
+		var webhook *applybuildv1.WebHookTriggerApplyConfiguration
+		var params string
+		triggerType := buildwebhook.NormalizeTriggerType(*trigger.Type)
+		switch triggerType {
+		case buildv1.GenericWebHookBuildTriggerType:
+			webhook, params = trigger.GenericWebHook, "generic"
+		case buildv1.GitHubWebHookBuildTriggerType:
+			webhook, params = trigger.GitHubWebHook, "github"
+		case buildv1.GitLabWebHookBuildTriggerType:
+			webhook, params = trigger.GitLabWebHook, "gitlab"
+		case buildv1.BitbucketWebHookBuildTriggerType:
+			webhook, params = trigger.BitbucketWebHook, "bitbucket"
+		case buildv1.ImageChangeBuildTriggerType:
+			params = "imageChange"
+			if trigger.ImageChange == nil {
+				errs = append(errs, field.Required(triggerPath.Child(params), ""))
+				continue
+			}
+			if from := trigger.ImageChange.From; from == nil {
+				emptyImageChange++
+				if strategyFrom := builderImage(spec.Strategy); strategyFrom == nil || strategyFrom.Kind != "ImageStreamTag" {
+					errs = append(errs, field.Invalid(triggerPath.Child(params, "from"), nil, "a default image change trigger requires a strategy builder image of kind ImageStreamTag"))
+				}
+			} else if from.Kind != "ImageStreamTag" {
+				errs = append(errs, field.NotSupported(triggerPath.Child(params, "from", "kind"), from.Kind, []string{"ImageStreamTag"}))
+			} else {
+				errs = append(errs, validateImageStreamTagName(from.Name, triggerPath.Child(params, "from", "name"))...)
+			}
+		case buildv1.ConfigChangeBuildTriggerType:
+		default:
+			errs = append(errs, field.Invalid(triggerPath.Child("type"), *trigger.Type, "invalid trigger type"))
+			continue
+		}
+		if set > 1 || (set == 1 && len(params) == 0) {
+			errs = append(errs, field.Invalid(triggerPath, *trigger.Type, "must provide only the parameters matching the trigger type"))
+		}
+
+		if len(params) == 0 || triggerType == buildv1.ImageChangeBuildTriggerType {
+			continue
+		}
+		if webhook == nil {
+			errs = append(errs, field.Required(triggerPath.Child(params), ""))
+			continue
+		}
+		if (webhook.Secret == nil || len(*webhook.Secret) == 0) && (webhook.SecretReference == nil || webhook.SecretReference.Name == nil || len(*webhook.SecretReference.Name) == 0) {
+			errs = append(errs, field.Required(triggerPath.Child(params, "secretReference"), "must provide a secret or a secret reference"))
+		}
+		if webhook.AllowEnv != nil && *webhook.AllowEnv && triggerType != buildv1.GenericWebHookBuildTriggerType {
+			errs = append(errs, field.Invalid(triggerPath.Child(params, "allowEnv"), true, "may only be set for generic webhooks"))
+		}
+	}
+	if emptyImageChange > 1 {
+		errs = append(errs, field.Invalid(fldPath, emptyImageChange, "only one image change trigger may omit from"))
+	}
+	return errs
+}
+
+func builderImage(strategy *applybuildv1.BuildStrategyApplyConfiguration) *corev1.ObjectReference {
+	switch {
+	case strategy == nil:
+		return nil
+	case strategy.SourceStrategy != nil:
+		return strategy.SourceStrategy.From
+	case strategy.DockerStrategy != nil:
+		return strategy.DockerStrategy.From
+	case strategy.CustomStrategy != nil:
+		return strategy.CustomStrategy.From
+	}
+	return nil
+}
+
+// escapes returns true if the relative path p refers to a location outside of its root.
+func escapes(p string) bool {
+	cleaned := path.Clean(p)
+	return cleaned == ".." || strings.HasPrefix(cleaned, "../")
+}
@@ -0,0 +1,177 @@
+package buildconfig
+
+import (
+	reflect "reflect"
+	sort "sort"
+	testing "testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	applybuildv1 "github.com/openshift/client-go/build/applyconfigurations/build/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidate(t *testing.T) {
+	builderImage := corev1.ObjectReference{Kind: "ImageStreamTag", Name: "ruby:3.3"}
+	source := func() *Builder {
+		return Source("app", "ns", builderImage).WithGitSource("https://git.example.com/app.git", "")
+	}
+	withTrigger := func(trigger *applybuildv1.BuildTriggerPolicyApplyConfiguration) *applybuildv1.BuildConfigApplyConfiguration {
+		config := source().ApplyConfiguration()
+		config.Spec.WithTriggers(trigger)
+		return config
+	}
+	tests := []struct {
+		name   string
+		config *applybuildv1.BuildConfigApplyConfiguration
+		// want are the paths of the expected errors.
+		want []string
+	}{
+		{
+			name:   "source build with every trigger",
+			config: source().WithOutputImageStreamTag("app:latest").WithConfigChangeTrigger().WithImageChangeTrigger(nil).WithWebHookTrigger(buildv1.GitHubWebHookBuildTriggerType, "webhook").ApplyConfiguration(),
+		},
+		{
+			name:   "deprecated webhook types",
+			config: source().WithWebHookTrigger(buildv1.GenericWebHookBuildTriggerTypeDeprecated, "webhook").WithWebHookTrigger(buildv1.GitHubWebHookBuildTriggerTypeDeprecated, "webhook").ApplyConfiguration(),
+		},
+		{
+			name: "deprecated type with its parameters set directly",
+			config: withTrigger(applybuildv1.BuildTriggerPolicy().
+				WithType(buildv1.GenericWebHookBuildTriggerTypeDeprecated).
+				WithGenericWebHook(applybuildv1.WebHookTrigger().WithSecret("s3cret").WithAllowEnv(true))),
+		},
+		{
+			name: "parameters of another trigger type",
+			config: withTrigger(applybuildv1.BuildTriggerPolicy().
+				WithType(buildv1.GitHubWebHookBuildTriggerType).
+				WithGenericWebHook(applybuildv1.WebHookTrigger().WithSecret("s3cret"))),
+			want: []string{"spec.triggers[0].github"},
+		},
+		{
+			name:   "missing source",
+			config: Source("app", "ns", builderImage).ApplyConfiguration(),
+			want:   []string{"spec.source"},
+		},
+		{
+			name:   "missing name and unsupported builder image kind",
+			config: Source("", "ns", corev1.ObjectReference{Kind: "Pod", Name: "x"}).WithGitSource("https://git.example.com/app.git", "").ApplyConfiguration(),
+			want:   []string{"metadata.name", "spec.strategy.sourceStrategy.from.kind"},
+		},
+		{
+			name:   "escaping context dir and invalid git uri",
+			config: Docker("app", "ns").WithGitSource("not a url", "").WithContextDir("../other").ApplyConfiguration(),
+			want:   []string{"spec.source.contextDir", "spec.source.git.uri"},
+		},
+		{
+			name:   "scp-like git uri",
+			config: Docker("app", "ns").WithGitSource("git@github.com:org/app.git", "").ApplyConfiguration(),
+		},
+		{
+			name:   "binary and git source",
+			config: Docker("app", "ns").WithGitSource("https://git.example.com/app.git", "").WithBinarySource("../app.jar").ApplyConfiguration(),
+			want:   []string{"spec.source.binary.asFile", "spec.source.git"},
+		},
+		{
+			name:   "dockerfile with a source strategy",
+			config: source().WithDockerfile("FROM scratch").ApplyConfiguration(),
+			want:   []string{"spec.source.dockerfile"},
+		},
+		{
+			name:   "output tag without tag",
+			config: source().WithOutputImageStreamTag("app").ApplyConfiguration(),
+			want:   []string{"spec.output.to.name"},
+		},
+		{
+			name:   "default image change trigger without an image stream builder image",
+			config: Docker("app", "ns").WithDockerfile("FROM scratch").WithImageChangeTrigger(nil).ApplyConfiguration(),
+			want:   []string{"spec.triggers[0].imageChange.from"},
+		},
+		{
+			name:   "two default image change triggers",
+			config: source().WithImageChangeTrigger(nil).WithImageChangeTrigger(nil).ApplyConfiguration(),
+			want:   []string{"spec.triggers"},
+		},
+		{
+			name:   "webhook without secret",
+			config: source().WithWebHookTrigger(buildv1.GitLabWebHookBuildTriggerType, "").ApplyConfiguration(),
+			want:   []string{"spec.triggers[0].gitlab.secretReference"},
+		},
+		{
+			name:   "unknown webhook type",
+			config: source().WithWebHookTrigger("Gitea", "webhook").ApplyConfiguration(),
+			want:   []string{"spec.triggers[0].type"},
+		},
+		{
+			name:   "pipeline without jenkinsfile",
+			config: JenkinsPipeline("app", "ns").ApplyConfiguration(),
+			want:   []string{"spec.strategy.jenkinsPipelineStrategy.jenkinsfile"},
+		},
+		{
+			name:   "pipeline with both jenkinsfile and path",
+			config: JenkinsPipeline("app", "ns").WithGitSource("https://git.example.com/app.git", "").WithJenkinsfile("node {}", "ci/Jenkinsfile").ApplyConfiguration(),
+			want:   []string{"spec.strategy.jenkinsPipelineStrategy.jenkinsfilePath"},
+		},
+		{
+			name:   "negative history limits",
+			config: source().WithHistoryLimits(-1, -1).ApplyConfiguration(),
+			want:   []string{"spec.failedBuildsHistoryLimit", "spec.successfulBuildsHistoryLimit"},
+		},
+		{
+			name:   "unknown run policy",
+			config: source().WithRunPolicy("Sometimes").ApplyConfiguration(),
+			want:   []string{"spec.runPolicy"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, err := range Validate(test.config) {
+				got = append(got, err.Field)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected errors for %v, got %v", test.want, Validate(test.config))
+			}
+		})
+	}
+}
+
+func TestWithWebHookTrigger(t *testing.T) {
+	tests := []struct {
+		triggerType buildv1.BuildTriggerType
+		want        buildv1.BuildTriggerType
+		webhook     func(*applybuildv1.BuildTriggerPolicyApplyConfiguration) *applybuildv1.WebHookTriggerApplyConfiguration
+	}{
+		{
+			triggerType: buildv1.GenericWebHookBuildTriggerTypeDeprecated,
+			want:        buildv1.GenericWebHookBuildTriggerType,
+			webhook: func(p *applybuildv1.BuildTriggerPolicyApplyConfiguration) *applybuildv1.WebHookTriggerApplyConfiguration {
+				return p.GenericWebHook
+			},
+		},
+		{
+			triggerType: buildv1.GitHubWebHookBuildTriggerTypeDeprecated,
+			want:        buildv1.GitHubWebHookBuildTriggerType,
+			webhook: func(p *applybuildv1.BuildTriggerPolicyApplyConfiguration) *applybuildv1.WebHookTriggerApplyConfiguration {
+				return p.GitHubWebHook
+			},
+		},
+		{
+			triggerType: buildv1.BitbucketWebHookBuildTriggerType,
+			want:        buildv1.BitbucketWebHookBuildTriggerType,
+			webhook: func(p *applybuildv1.BuildTriggerPolicyApplyConfiguration) *applybuildv1.WebHookTriggerApplyConfiguration {
+				return p.BitbucketWebHook
+			},
+		},
+	}
+	for _, test := range tests {
+		triggers := Docker("app", "ns").WithWebHookTrigger(test.triggerType, "webhook").ApplyConfiguration().Spec.Triggers
+		if len(triggers) != 1 || *triggers[0].Type != test.want {
+			t.Errorf("%s: expected a single %s trigger, got %v", test.triggerType, test.want, triggers)
+			continue
+		}
+		if webhook := test.webhook(&triggers[0]); webhook == nil || *webhook.SecretReference.Name != "webhook" {
+			t.Errorf("%s: expected the %s parameters to be set, got %#v", test.triggerType, test.want, triggers[0])
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Package cancel cancels running builds the same way oc cancel-build does.
+package cancel
+
+import (
+	context "context"
+	fmt "fmt"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	typedbuildv1 "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
+	listersbuildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	waiter "github.com/openshift/client-go/build/waiter"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	retry "k8s.io/client-go/util/retry"
+)
+
+// Outcome describes what happened to a build when cancelling it.
+type Outcome string
+
+const (
+	// OutcomeCancelled means the cancellation of the build was requested.
+	OutcomeCancelled Outcome = "Cancelled"
+	// OutcomeAlreadyCancelled means the build was cancelled before.
+	OutcomeAlreadyCancelled Outcome = "AlreadyCancelled"
+	// OutcomeAlreadyFinished means the build finished before it could be cancelled.
+	OutcomeAlreadyFinished Outcome = "AlreadyFinished"
+	// OutcomeFailed means the cancellation failed, see Result.Err.
+	OutcomeFailed Outcome = "Failed"
+)
+
+// Result is the outcome of cancelling a single build.
+type Result struct {
+	Namespace string
+	Name      string
+	Outcome   Outcome
+	// Build is the last known state of the build, nil if it could not be retrieved.
+	Build *buildv1.Build
+	Err   error
+}
+
+// DefaultPhases are the phases of builds that are cancelled when no phases are given.
+var DefaultPhases = []buildv1.BuildPhase{buildv1.BuildPhaseNew, buildv1.BuildPhasePending, buildv1.BuildPhaseRunning}
+
+// Canceller cancels builds.
+type Canceller struct {
+	client typedbuildv1.BuildsGetter
+	lister listersbuildv1.BuildLister
+}
+
+// NewCanceller returns a Canceller that updates builds through client and finds the builds of
+// build configs through lister.
+func NewCanceller(client typedbuildv1.BuildsGetter, lister listersbuildv1.BuildLister) *Canceller {
+	return &Canceller{client: client, lister: lister}
+}
+
+// CancelBuild requests the cancellation of the named build, retrying on conflicts.
+func (c *Canceller) CancelBuild(ctx context.Context, namespace, name string) Result {
+	result := Result{Namespace: namespace, Name: name}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		build, err := c.client.Builds(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		result.Build = build
+		switch {
+		case build.Status.Cancelled || build.Status.Phase == buildv1.BuildPhaseCancelled:
+			result.Outcome = OutcomeAlreadyCancelled
+			return nil
+		case waiter.IsTerminal(build.Status.Phase):
+			result.Outcome = OutcomeAlreadyFinished
+			return nil
+		}
+
+		build = build.DeepCopy()
+		build.Status.Cancelled = true
+		updated, err := c.client.Builds(namespace).UpdateStatus(ctx, build, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+		result.Build = updated
+		result.Outcome = OutcomeCancelled
+		return nil
+	})
+	if err != nil {
+		result.Outcome = OutcomeFailed
+		result.Err = err
+	}
+	return result
+}
+
+// CancelBuildsForConfig requests the cancellation of the builds of the named build config that
+// are in one of the given phases, or in DefaultPhases when none are given. It returns the outcome
+// for every matching build, or an error if the builds could not be listed.
+func (c *Canceller) CancelBuildsForConfig(ctx context.Context, namespace, buildConfigName string, phases ...buildv1.BuildPhase) ([]Result, error) {
+	if len(phases) == 0 {
+		phases = DefaultPhases
+	}
+	builds, err := c.lister.Builds(namespace).ListForBuildConfig(buildConfigName)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, build := range builds {
+		if !hasPhase(build.Status.Phase, phases) {
+			continue
+		}
+		results = append(results, c.CancelBuild(ctx, namespace, build.Name))
+	}
+	return results, nil
+}
+
+// Error returns an error summarizing the failed results, or nil if none failed.
+func Error(results []Result) error {
+	var failed []Result
+	for _, result := range results {
+		if result.Outcome == OutcomeFailed {
+			failed = append(failed, result)
+		}
+	}
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("failed to cancel build %s/%s: %w", failed[0].Namespace, failed[0].Name, failed[0].Err)
+	}
+	return fmt.Errorf("failed to cancel %d builds, first error for %s/%s: %w", len(failed), failed[0].Namespace, failed[0].Name, failed[0].Err)
+}
+
+func hasPhase(phase buildv1.BuildPhase, phases []buildv1.BuildPhase) bool {
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package cancel
+
+import (
+	context "context"
+	errors "errors"
+	reflect "reflect"
+	sort "sort"
+	strings "strings"
+	testing "testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	fake "github.com/openshift/client-go/build/clientset/versioned/fake"
+	listersbuildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// phases describes the builds of build config "app" in namespace "ns" by name.
+type phases map[string]buildv1.BuildPhase
+
+// objects returns the builds described by p, with status.cancelled set on cancelled builds.
+func (p phases) objects() []runtime.Object {
+	var objects []runtime.Object
+	for name, phase := range p {
+		objects = append(objects, &buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "ns",
+				Name:        name,
+				Labels:      map[string]string{buildv1.BuildConfigLabel: "app"},
+				Annotations: map[string]string{buildv1.BuildConfigAnnotation: "app"},
+			},
+			Status: buildv1.BuildStatus{Phase: phase, Cancelled: phase == buildv1.BuildPhaseCancelled},
+		})
+	}
+	return objects
+}
+
+func newCanceller(t *testing.T, builds phases) (*Canceller, *fake.Clientset) {
+	t.Helper()
+	objects := builds.objects()
+	client := fake.NewSimpleClientset(objects...)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range objects {
+		if err := indexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewCanceller(client.BuildV1(), listersbuildv1.NewBuildLister(indexer)), client
+}
+
+func TestCancelBuild(t *testing.T) {
+	tests := []struct {
+		name          string
+		phase         buildv1.BuildPhase
+		want          Outcome
+		wantCancelled bool
+	}{
+		{name: "new", phase: buildv1.BuildPhaseNew, want: OutcomeCancelled, wantCancelled: true},
+		{name: "running", phase: buildv1.BuildPhaseRunning, want: OutcomeCancelled, wantCancelled: true},
+		{name: "cancelled", phase: buildv1.BuildPhaseCancelled, want: OutcomeAlreadyCancelled, wantCancelled: true},
+		{name: "complete", phase: buildv1.BuildPhaseComplete, want: OutcomeAlreadyFinished},
+		{name: "failed", phase: buildv1.BuildPhaseFailed, want: OutcomeAlreadyFinished},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			canceller, client := newCanceller(t, phases{"app-1": test.phase})
+			result := canceller.CancelBuild(context.TODO(), "ns", "app-1")
+			if result.Outcome != test.want || result.Err != nil {
+				t.Fatalf("expected %s, got %s: %v", test.want, result.Outcome, result.Err)
+			}
+			build, err := client.BuildV1().Builds("ns").Get(context.TODO(), "app-1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if build.Status.Cancelled != test.wantCancelled {
+				t.Errorf("expected status.cancelled %t, got %t", test.wantCancelled, build.Status.Cancelled)
+			}
+		})
+	}
+}
+
+func TestCancelBuildRetriesConflicts(t *testing.T) {
+	canceller, client := newCanceller(t, phases{"app-1": buildv1.BuildPhaseRunning})
+	conflicts := 2
+	client.PrependReactor("update", "builds", func(clienttesting.Action) (bool, runtime.Object, error) {
+		if conflicts == 0 {
+			return false, nil, nil
+		}
+		conflicts--
+		return true, nil, apierrors.NewConflict(buildv1.Resource("builds"), "app-1", errors.New("modified"))
+	})
+
+	if result := canceller.CancelBuild(context.TODO(), "ns", "app-1"); result.Outcome != OutcomeCancelled {
+		t.Fatalf("expected the build to be cancelled after retries, got %s: %v", result.Outcome, result.Err)
+	}
+	if conflicts != 0 {
+		t.Errorf("expected all conflicts to be retried, %d left", conflicts)
+	}
+}
+
+func TestCancelBuildMissing(t *testing.T) {
+	canceller, _ := newCanceller(t, nil)
+	result := canceller.CancelBuild(context.TODO(), "ns", "app-1")
+	if result.Outcome != OutcomeFailed || !apierrors.IsNotFound(result.Err) {
+		t.Fatalf("expected a not found failure, got %s: %v", result.Outcome, result.Err)
+	}
+	if err := Error([]Result{result}); err == nil || !strings.Contains(err.Error(), "failed to cancel build ns/app-1") {
+		t.Errorf("unexpected summary %v", err)
+	}
+}
+
+func TestCancelBuildsForConfig(t *testing.T) {
+	builds := phases{
+		"app-1": buildv1.BuildPhaseComplete,
+		"app-2": buildv1.BuildPhaseFailed,
+		"app-3": buildv1.BuildPhasePending,
+		"app-4": buildv1.BuildPhaseRunning,
+		"app-5": buildv1.BuildPhaseNew,
+	}
+	tests := []struct {
+		name   string
+		phases []buildv1.BuildPhase
+		want   []string
+	}{
+		{name: "default phases", want: []string{"app-3", "app-4", "app-5"}},
+		{name: "only new", phases: []buildv1.BuildPhase{buildv1.BuildPhaseNew}, want: []string{"app-5"}},
+		{name: "finished", phases: []buildv1.BuildPhase{buildv1.BuildPhaseComplete}, want: []string{"app-1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			canceller, _ := newCanceller(t, builds)
+			results, err := canceller.CancelBuildsForConfig(context.TODO(), "ns", "app", test.phases...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.Name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v to be cancelled, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("forbidden")
+	results := []Result{
+		{Namespace: "ns", Name: "app-1", Outcome: OutcomeCancelled},
+		{Namespace: "ns", Name: "app-2", Outcome: OutcomeFailed, Err: cause},
+		{Namespace: "ns", Name: "app-3", Outcome: OutcomeFailed, Err: errors.New("gone")},
+	}
+	if err := Error(results[:1]); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	err := Error(results)
+	if !errors.Is(err, cause) || !strings.Contains(err.Error(), "failed to cancel 2 builds") {
+		t.Errorf("unexpected summary %v", err)
+	}
+}
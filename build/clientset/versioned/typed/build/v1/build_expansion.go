@@ -0,0 +1,20 @@
+package v1
+
+import (
+	buildv1 "github.com/openshift/api/build/v1"
+	scheme "github.com/openshift/client-go/build/clientset/versioned/scheme"
+	rest "k8s.io/client-go/rest"
+)
+
+// The BuildExpansion interface allows manually adding extra methods to the BuildInterface.
+type BuildExpansion interface {
+	GetLogs(name string, opts *buildv1.BuildLogOptions) *rest.Request
+}
+
+// GetLogs constructs a request for getting the logs for a build.
+func (c *builds) GetLogs(name string, opts *buildv1.BuildLogOptions) *rest.Request {
+	if opts == nil {
+		opts = &buildv1.BuildLogOptions{}
+	}
+	return c.GetClient().Get().Namespace(c.GetNamespace()).Resource("builds").Name(name).SubResource("log").VersionedParams(opts, scheme.ParameterCodec)
+}
@@ -0,0 +1,73 @@
+package v1
+
+import (
+	context "context"
+	fmt "fmt"
+	io "io"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	scheme "github.com/openshift/client-go/build/clientset/versioned/scheme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rest "k8s.io/client-go/rest"
+)
+
+// The BuildConfigExpansion interface allows manually adding extra methods to the BuildConfigInterface.
+type BuildConfigExpansion interface {
+	GetLogs(ctx context.Context, name string, opts *buildv1.BuildLogOptions) (*rest.Request, error)
+	InstantiateBinary(ctx context.Context, name string, opts *buildv1.BinaryBuildRequestOptions, r io.Reader) (*buildv1.Build, error)
+}
+
+// GetLogs constructs a request for getting the logs for a build of the named build config.
+// The build is selected by opts.Version or, when opts or its version is unset, by the latest version
+// of the build config.
+func (c *buildConfigs) GetLogs(ctx context.Context, name string, opts *buildv1.BuildLogOptions) (*rest.Request, error) {
+	if opts == nil {
+		opts = &buildv1.BuildLogOptions{}
+	}
+	buildName, err := BuildNameForLogs(ctx, c, c.GetNamespace(), name, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetClient().Get().Namespace(c.GetNamespace()).Resource("builds").Name(buildName).SubResource("log").VersionedParams(opts, scheme.ParameterCodec), nil
+}
+
+// InstantiateBinary streams the contents of r, a single file or an archive, to the named build config and
+// starts a binary build from it. Returns the server's representation of the build, and an error, if there is any.
+func (c *buildConfigs) InstantiateBinary(ctx context.Context, name string, opts *buildv1.BinaryBuildRequestOptions, r io.Reader) (result *buildv1.Build, err error) {
+	result = &buildv1.Build{}
+	err = c.GetClient().Post().
+		Namespace(c.GetNamespace()).
+		Resource("buildconfigs").
+		Name(name).
+		SubResource("instantiatebinary").
+		VersionedParams(opts, scheme.ParameterCodec).
+		Body(r).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// BuildNameForConfigVersion returns the name of the build created by the given version of a build config.
+func BuildNameForConfigVersion(name string, version int64) string {
+	return fmt.Sprintf("%s-%d", name, version)
+}
+
+// BuildNameForLogs returns the name of the build of the named build config whose logs opts select:
+// the build of opts.Version or, when opts or its version is unset, the build of the latest version
+// read from buildConfigs. Implementations of BuildConfigExpansion.GetLogs share it.
+func BuildNameForLogs(ctx context.Context, buildConfigs BuildConfigInterface, namespace, name string, opts *buildv1.BuildLogOptions) (string, error) {
+	var version int64
+	if opts != nil && opts.Version != nil {
+		version = *opts.Version
+	} else {
+		buildConfig, err := buildConfigs.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		version = buildConfig.Status.LastVersion
+	}
+	if version <= 0 {
+		return "", fmt.Errorf("build config %s/%s has no builds", namespace, name)
+	}
+	return BuildNameForConfigVersion(name, version), nil
+}
@@ -0,0 +1,48 @@
+package fake
+
+import (
+	fmt "fmt"
+	io "io"
+	http "net/http"
+	strings "strings"
+
+	v1 "github.com/openshift/api/build/v1"
+	scheme "github.com/openshift/client-go/build/clientset/versioned/scheme"
+	rest "k8s.io/client-go/rest"
+	fakerest "k8s.io/client-go/rest/fake"
+	testing "k8s.io/client-go/testing"
+)
+
+func (c *fakeBuilds) GetLogs(name string, opts *v1.BuildLogOptions) *rest.Request {
+	action := testing.GenericActionImpl{}
+	action.Verb = "get"
+	action.Namespace = c.Namespace()
+	action.Resource = c.Resource()
+	action.Subresource = "log"
+	action.Value = opts
+
+	// GetLogs cannot return an error, so a reactor error fails the request when it is executed.
+	_, err := c.Fake.Invokes(action, &v1.Build{})
+	return newFakeLogRequest(fmt.Sprintf("/apis/build.openshift.io/v1/namespaces/%s/builds/%s/log", c.Namespace(), name), err)
+}
+
+// newFakeLogRequest returns a request whose response body is a fixed log stream, or that fails with
+// err if it is not nil.
+func newFakeLogRequest(path string, err error) *rest.Request {
+	fakeClient := &fakerest.RESTClient{
+		Client: fakerest.CreateHTTPClient(func(request *http.Request) (*http.Response, error) {
+			if err != nil {
+				return nil, err
+			}
+			resp := &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader("fake logs")),
+			}
+			return resp, nil
+		}),
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         v1.SchemeGroupVersion,
+		VersionedAPIPath:     path,
+	}
+	return fakeClient.Request()
+}
@@ -0,0 +1,46 @@
+package fake
+
+import (
+	context "context"
+	fmt "fmt"
+	io "io"
+
+	v1 "github.com/openshift/api/build/v1"
+	typedbuildv1 "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rest "k8s.io/client-go/rest"
+	testing "k8s.io/client-go/testing"
+)
+
+func (c *fakeBuildConfigs) GetLogs(ctx context.Context, name string, opts *v1.BuildLogOptions) (*rest.Request, error) {
+	if opts == nil {
+		opts = &v1.BuildLogOptions{}
+	}
+	buildName, err := typedbuildv1.BuildNameForLogs(ctx, c, c.Namespace(), name, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	action := testing.GenericActionImpl{}
+	action.Verb = "get"
+	action.Namespace = c.Namespace()
+	action.Resource = v1.SchemeGroupVersion.WithResource("builds")
+	action.Subresource = "log"
+	action.Value = opts
+
+	if _, err := c.Fake.Invokes(action, &v1.Build{}); err != nil {
+		return nil, err
+	}
+	return newFakeLogRequest(fmt.Sprintf("/apis/build.openshift.io/v1/namespaces/%s/builds/%s/log", c.Namespace(), buildName), nil), nil
+}
+
+func (c *fakeBuildConfigs) InstantiateBinary(ctx context.Context, name string, opts *v1.BinaryBuildRequestOptions, r io.Reader) (result *v1.Build, err error) {
+	emptyResult := &v1.Build{}
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateSubresourceActionWithOptions(c.Resource(), name, "instantiatebinary", c.Namespace(), opts, metav1.CreateOptions{}), emptyResult)
+
+	if obj == nil {
+		return emptyResult, err
+	}
+	return obj.(*v1.Build), err
+}
@@ -1,7 +1,3 @@
 // Code generated by client-gen. DO NOT EDIT.
 
 package v1
-
-type BuildExpansion interface{}
-
-type BuildConfigExpansion interface{}
@@ -0,0 +1,165 @@
+package graph
+
+import (
+	strings "strings"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	listersappsv1 "github.com/openshift/client-go/apps/listers/apps/v1"
+	listersbuildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	strategy "github.com/openshift/client-go/build/strategy"
+	listersimagev1 "github.com/openshift/client-go/image/listers/image/v1"
+	corev1 "k8s.io/api/core/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+)
+
+// Builder reads build configs, image streams and, optionally, deployment configs from listers
+// and assembles their dependency graph.
+type Builder struct {
+	buildConfigs      listersbuildv1.BuildConfigLister
+	imageStreams      listersimagev1.ImageStreamLister
+	deploymentConfigs listersappsv1.DeploymentConfigLister
+}
+
+// NewBuilder returns a Builder. deploymentConfigs may be nil to leave deployment configs out of
+// the graph.
+func NewBuilder(buildConfigs listersbuildv1.BuildConfigLister, imageStreams listersimagev1.ImageStreamLister, deploymentConfigs listersappsv1.DeploymentConfigLister) *Builder {
+	return &Builder{
+		buildConfigs:      buildConfigs,
+		imageStreams:      imageStreams,
+		deploymentConfigs: deploymentConfigs,
+	}
+}
+
+// Build returns the dependency graph of the objects in namespace, or of all namespaces when
+// namespace is empty.
+func (b *Builder) Build(namespace string) (*Graph, error) {
+	g := New()
+
+	var buildConfigs []*buildv1.BuildConfig
+	var err error
+	if len(namespace) > 0 {
+		buildConfigs, err = b.buildConfigs.BuildConfigs(namespace).List(labels.Everything())
+	} else {
+		buildConfigs, err = b.buildConfigs.List(labels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, buildConfig := range buildConfigs {
+		addBuildConfig(g, buildConfig)
+	}
+
+	var imageStreams []*imagev1.ImageStream
+	if len(namespace) > 0 {
+		imageStreams, err = b.imageStreams.ImageStreams(namespace).List(labels.Everything())
+	} else {
+		imageStreams, err = b.imageStreams.List(labels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, imageStream := range imageStreams {
+		addImageStream(g, imageStream)
+	}
+
+	if b.deploymentConfigs == nil {
+		return g, nil
+	}
+	var deploymentConfigs []*appsv1.DeploymentConfig
+	if len(namespace) > 0 {
+		deploymentConfigs, err = b.deploymentConfigs.DeploymentConfigs(namespace).List(labels.Everything())
+	} else {
+		deploymentConfigs, err = b.deploymentConfigs.List(labels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, deploymentConfig := range deploymentConfigs {
+		addDeploymentConfig(g, deploymentConfig)
+	}
+	return g, nil
+}
+
+func addBuildConfig(g *Graph, buildConfig *buildv1.BuildConfig) {
+	node := Node{Kind: BuildConfigNode, Namespace: buildConfig.Namespace, Name: buildConfig.Name}
+	g.AddNode(node)
+
+	if to := buildConfig.Spec.Output.To; to != nil {
+		if output, ok := imageNode(to, buildConfig.Namespace); ok {
+			g.AddEdge(node, output, OutputEdge)
+		}
+	}
+	for _, trigger := range buildConfig.Spec.Triggers {
+		if trigger.ImageChange == nil {
+			continue
+		}
+		from := trigger.ImageChange.From
+		if from == nil {
+			from = strategy.BuilderImage(&buildConfig.Spec.Strategy)
+		}
+		if from == nil || from.Kind != "ImageStreamTag" {
+			continue
+		}
+		if input, ok := imageNode(from, buildConfig.Namespace); ok {
+			g.AddEdge(input, node, TriggerEdge)
+		}
+	}
+}
+
+func addImageStream(g *Graph, imageStream *imagev1.ImageStream) {
+	for _, tag := range imageStream.Spec.Tags {
+		if tag.From == nil || tag.From.Kind != "ImageStreamTag" {
+			continue
+		}
+		source := *tag.From
+		// a bare tag name refers to a tag of the same image stream
+		if !strings.Contains(source.Name, ":") {
+			source.Name = imageStream.Name + ":" + source.Name
+		}
+		from, ok := imageNode(&source, imageStream.Namespace)
+		if !ok {
+			continue
+		}
+		to := Node{Kind: ImageStreamTagNode, Namespace: imageStream.Namespace, Name: imageStream.Name + ":" + tag.Name}
+		g.AddEdge(from, to, TagEdge)
+	}
+}
+
+func addDeploymentConfig(g *Graph, deploymentConfig *appsv1.DeploymentConfig) {
+	node := Node{Kind: DeploymentConfigNode, Namespace: deploymentConfig.Namespace, Name: deploymentConfig.Name}
+	g.AddNode(node)
+	for _, trigger := range deploymentConfig.Spec.Triggers {
+		if trigger.Type != appsv1.DeploymentTriggerOnImageChange || trigger.ImageChangeParams == nil {
+			continue
+		}
+		from := trigger.ImageChangeParams.From
+		if from.Kind != "ImageStreamTag" {
+			continue
+		}
+		if input, ok := imageNode(&from, deploymentConfig.Namespace); ok {
+			g.AddEdge(input, node, TriggerEdge)
+		}
+	}
+}
+
+// imageNode returns the node for an image reference, resolving its namespace against
+// defaultNamespace.
+func imageNode(ref *corev1.ObjectReference, defaultNamespace string) (Node, bool) {
+	switch ref.Kind {
+	case "ImageStreamTag":
+		namespace := ref.Namespace
+		if len(namespace) == 0 {
+			namespace = defaultNamespace
+		}
+		name := ref.Name
+		if !strings.Contains(name, ":") {
+			name += ":latest"
+		}
+		return Node{Kind: ImageStreamTagNode, Namespace: namespace, Name: name}, true
+	case "DockerImage":
+		return Node{Kind: DockerImageNode, Name: ref.Name}, true
+	}
+	return Node{}, false
+}
@@ -0,0 +1,250 @@
+// Package graph builds the dependency graph between build configs, the image stream tags they
+// produce and the build and deployment configs those tags trigger.
+package graph
+
+import (
+	json "encoding/json"
+	fmt "fmt"
+	io "io"
+	sort "sort"
+)
+
+// NodeKind is the kind of object a node represents.
+type NodeKind string
+
+const (
+	BuildConfigNode      NodeKind = "BuildConfig"
+	DeploymentConfigNode NodeKind = "DeploymentConfig"
+	ImageStreamTagNode   NodeKind = "ImageStreamTag"
+	// DockerImageNode is an image outside of any image stream, identified by its pull spec.
+	DockerImageNode NodeKind = "DockerImage"
+)
+
+// Node is a build config, deployment config, image stream tag or external image.
+type Node struct {
+	Kind      NodeKind `json:"kind"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name"`
+}
+
+// String returns a unique, human readable identifier of the node.
+func (n Node) String() string {
+	if len(n.Namespace) == 0 {
+		return fmt.Sprintf("%s/%s", n.Kind, n.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", n.Kind, n.Namespace, n.Name)
+}
+
+// EdgeKind describes how the source of an edge affects its target.
+type EdgeKind string
+
+const (
+	// OutputEdge links a build config to the image it pushes.
+	OutputEdge EdgeKind = "Output"
+	// TriggerEdge links an image to the build or deployment config its changes trigger.
+	TriggerEdge EdgeKind = "ImageChangeTrigger"
+	// TagEdge links an image stream tag to the tag that tracks it.
+	TagEdge EdgeKind = "Tag"
+)
+
+// Edge is a directed dependency between two nodes.
+type Edge struct {
+	From Node     `json:"from"`
+	To   Node     `json:"to"`
+	Kind EdgeKind `json:"kind"`
+}
+
+// Graph is a directed graph of build dependencies.
+type Graph struct {
+	nodes map[Node]struct{}
+	edges map[Node][]Edge
+}
+
+// New returns an empty graph.
+func New() *Graph {
+	return &Graph{nodes: map[Node]struct{}{}, edges: map[Node][]Edge{}}
+}
+
+// AddNode adds node to the graph if it is not part of it yet.
+func (g *Graph) AddNode(node Node) {
+	g.nodes[node] = struct{}{}
+}
+
+// AddEdge adds an edge of the given kind, and its nodes, to the graph.
+func (g *Graph) AddEdge(from, to Node, kind EdgeKind) {
+	g.AddNode(from)
+	g.AddNode(to)
+	for _, edge := range g.edges[from] {
+		if edge.To == to && edge.Kind == kind {
+			return
+		}
+	}
+	g.edges[from] = append(g.edges[from], Edge{From: from, To: to, Kind: kind})
+}
+
+// Nodes returns all nodes of the graph, sorted.
+func (g *Graph) Nodes() []Node {
+	nodes := make([]Node, 0, len(g.nodes))
+	for node := range g.nodes {
+		nodes = append(nodes, node)
+	}
+	sortNodes(nodes)
+	return nodes
+}
+
+// Edges returns all edges of the graph, sorted by source and target.
+func (g *Graph) Edges() []Edge {
+	var edges []Edge
+	for _, node := range g.Nodes() {
+		edges = append(edges, g.From(node)...)
+	}
+	return edges
+}
+
+// From returns the edges leaving node, sorted by target.
+func (g *Graph) From(node Node) []Edge {
+	edges := append([]Edge(nil), g.edges[node]...)
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].To != edges[j].To {
+			return edges[i].To.String() < edges[j].To.String()
+		}
+		return edges[i].Kind < edges[j].Kind
+	})
+	return edges
+}
+
+// Downstream returns every node reachable from node, that is everything that is rebuilt or
+// redeployed when node changes, sorted.
+func (g *Graph) Downstream(node Node) []Node {
+	visited := map[Node]bool{node: true}
+	queue := []Node{node}
+	var downstream []Node
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range g.edges[current] {
+			if visited[edge.To] {
+				continue
+			}
+			visited[edge.To] = true
+			downstream = append(downstream, edge.To)
+			queue = append(queue, edge.To)
+		}
+	}
+	sortNodes(downstream)
+	return downstream
+}
+
+// Cycles returns the groups of nodes that depend on each other, such as a build config that is
+// triggered by the tag it pushes. Each cycle is sorted and the cycles are ordered by their
+// first node.
+func (g *Graph) Cycles() [][]Node {
+	// Tarjan's strongly connected components algorithm.
+	index := 0
+	indices := map[Node]int{}
+	lowlinks := map[Node]int{}
+	onStack := map[Node]bool{}
+	var stack []Node
+	var cycles [][]Node
+
+	var connect func(node Node)
+	connect = func(node Node) {
+		indices[node] = index
+		lowlinks[node] = index
+		index++
+		stack = append(stack, node)
+		onStack[node] = true
+
+		selfLoop := false
+		for _, edge := range g.edges[node] {
+			if edge.To == node {
+				selfLoop = true
+			}
+			if _, visited := indices[edge.To]; !visited {
+				connect(edge.To)
+				lowlinks[node] = min(lowlinks[node], lowlinks[edge.To])
+			} else if onStack[edge.To] {
+				lowlinks[node] = min(lowlinks[node], indices[edge.To])
+			}
+		}
+
+		if lowlinks[node] != indices[node] {
+			return
+		}
+		var component []Node
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == node {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			sortNodes(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, node := range g.Nodes() {
+		if _, visited := indices[node]; !visited {
+			connect(node)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0].String() < cycles[j][0].String()
+	})
+	return cycles
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph builds {"); err != nil {
+		return err
+	}
+	for _, node := range g.Nodes() {
+		if _, err := fmt.Fprintf(w, "  %q [label=%q, shape=%s];\n", node.String(), label(node), shape(node.Kind)); err != nil {
+			return err
+		}
+	}
+	for _, edge := range g.Edges() {
+		if _, err := fmt.Fprintf(w, "  %q -> %q [label=%q];\n", edge.From.String(), edge.To.String(), edge.Kind); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// MarshalJSON encodes the graph as its sorted lists of nodes and edges.
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Nodes []Node `json:"nodes"`
+		Edges []Edge `json:"edges"`
+	}{
+		Nodes: g.Nodes(),
+		Edges: g.Edges(),
+	})
+}
+
+func label(node Node) string {
+	if len(node.Namespace) == 0 {
+		return fmt.Sprintf("%s\n%s", node.Kind, node.Name)
+	}
+	return fmt.Sprintf("%s\n%s/%s", node.Kind, node.Namespace, node.Name)
+}
+
+func shape(kind NodeKind) string {
+	switch kind {
+	case BuildConfigNode, DeploymentConfigNode:
+		return "box"
+	}
+	return "ellipse"
+}
+
+func sortNodes(nodes []Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].String() < nodes[j].String()
+	})
+}
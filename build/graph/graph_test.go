@@ -0,0 +1,160 @@
+package graph
+
+import (
+	bytes "bytes"
+	fmt "fmt"
+	reflect "reflect"
+	strings "strings"
+	testing "testing"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	listersappsv1 "github.com/openshift/client-go/apps/listers/apps/v1"
+	listersbuildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	listersimagev1 "github.com/openshift/client-go/image/listers/image/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// edges renders the edges of g as "from -kind-> to".
+func edges(g *Graph) []string {
+	var rendered []string
+	for _, edge := range g.Edges() {
+		rendered = append(rendered, fmt.Sprintf("%s -%s-> %s", edge.From, edge.Kind, edge.To))
+	}
+	return rendered
+}
+
+func indexer(t *testing.T, objects ...runtime.Object) cache.Indexer {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range objects {
+		if err := indexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return indexer
+}
+
+func TestBuilder(t *testing.T) {
+	istag := func(name string) *corev1.ObjectReference {
+		return &corev1.ObjectReference{Kind: "ImageStreamTag", Name: name}
+	}
+	buildConfigs := indexer(t,
+		// triggered by the builder image of its source strategy
+		&buildv1.BuildConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+			Spec: buildv1.BuildConfigSpec{
+				CommonSpec: buildv1.CommonSpec{
+					Strategy: buildv1.BuildStrategy{SourceStrategy: &buildv1.SourceBuildStrategy{From: *istag("builder:1.0")}},
+					Output:   buildv1.BuildOutput{To: istag("app")},
+				},
+				Triggers: []buildv1.BuildTriggerPolicy{{Type: buildv1.ImageChangeBuildTriggerType, ImageChange: &buildv1.ImageChangeTrigger{}}},
+			},
+		},
+		// triggered by an explicit tag in another namespace, pushing to a docker image
+		&buildv1.BuildConfig{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "tools"},
+			Spec: buildv1.BuildConfigSpec{
+				CommonSpec: buildv1.CommonSpec{
+					Strategy: buildv1.BuildStrategy{DockerStrategy: &buildv1.DockerBuildStrategy{}},
+					Output:   buildv1.BuildOutput{To: &corev1.ObjectReference{Kind: "DockerImage", Name: "quay.io/org/tools:latest"}},
+				},
+				Triggers: []buildv1.BuildTriggerPolicy{{Type: buildv1.ImageChangeBuildTriggerType, ImageChange: &buildv1.ImageChangeTrigger{
+					From: &corev1.ObjectReference{Kind: "ImageStreamTag", Namespace: "openshift", Name: "base:9"},
+				}}},
+			},
+		},
+	)
+	imageStreams := indexer(t, &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+		Spec: imagev1.ImageStreamSpec{Tags: []imagev1.TagReference{
+			{Name: "prod", From: istag("latest")},
+			{Name: "external", From: &corev1.ObjectReference{Kind: "DockerImage", Name: "quay.io/org/app:1"}},
+		}},
+	})
+	deploymentConfigs := indexer(t, &appsv1.DeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "web"},
+		Spec: appsv1.DeploymentConfigSpec{Triggers: []appsv1.DeploymentTriggerPolicy{{
+			Type:              appsv1.DeploymentTriggerOnImageChange,
+			ImageChangeParams: &appsv1.DeploymentTriggerImageChangeParams{From: *istag("app:prod")},
+		}}},
+	})
+
+	builder := NewBuilder(listersbuildv1.NewBuildConfigLister(buildConfigs), listersimagev1.NewImageStreamLister(imageStreams), listersappsv1.NewDeploymentConfigLister(deploymentConfigs))
+	g, err := builder.Build("ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"BuildConfig/ns/app -Output-> ImageStreamTag/ns/app:latest",
+		"BuildConfig/ns/tools -Output-> DockerImage/quay.io/org/tools:latest",
+		"ImageStreamTag/ns/app:latest -Tag-> ImageStreamTag/ns/app:prod",
+		"ImageStreamTag/ns/app:prod -ImageChangeTrigger-> DeploymentConfig/ns/web",
+		"ImageStreamTag/ns/builder:1.0 -ImageChangeTrigger-> BuildConfig/ns/app",
+		"ImageStreamTag/openshift/base:9 -ImageChangeTrigger-> BuildConfig/ns/tools",
+	}
+	if got := edges(g); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected edges:\n%s", strings.Join(got, "\n"))
+	}
+
+	downstream := g.Downstream(Node{Kind: BuildConfigNode, Namespace: "ns", Name: "app"})
+	wantDownstream := []Node{
+		{Kind: DeploymentConfigNode, Namespace: "ns", Name: "web"},
+		{Kind: ImageStreamTagNode, Namespace: "ns", Name: "app:latest"},
+		{Kind: ImageStreamTagNode, Namespace: "ns", Name: "app:prod"},
+	}
+	if !reflect.DeepEqual(downstream, wantDownstream) {
+		t.Errorf("unexpected downstream %v", downstream)
+	}
+
+	// without deployment configs
+	g, err = NewBuilder(listersbuildv1.NewBuildConfigLister(buildConfigs), listersimagev1.NewImageStreamLister(imageStreams), nil).Build("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, edge := range edges(g) {
+		if strings.Contains(edge, string(DeploymentConfigNode)) {
+			t.Errorf("unexpected deployment config edge %s", edge)
+		}
+	}
+}
+
+func TestCycles(t *testing.T) {
+	bc := func(name string) Node { return Node{Kind: BuildConfigNode, Namespace: "ns", Name: name} }
+	tag := func(name string) Node { return Node{Kind: ImageStreamTagNode, Namespace: "ns", Name: name} }
+
+	g := New()
+	// a build config triggered by the tag it pushes
+	g.AddEdge(bc("loop"), tag("loop:latest"), OutputEdge)
+	g.AddEdge(tag("loop:latest"), bc("loop"), TriggerEdge)
+	// a chain without cycles
+	g.AddEdge(bc("a"), tag("a:latest"), OutputEdge)
+	g.AddEdge(tag("a:latest"), bc("b"), TriggerEdge)
+	// a tag tracking itself
+	g.AddEdge(tag("self:latest"), tag("self:latest"), TagEdge)
+	// duplicate edges are ignored
+	g.AddEdge(bc("a"), tag("a:latest"), OutputEdge)
+
+	want := [][]Node{
+		{bc("loop"), tag("loop:latest")},
+		{tag("self:latest")},
+	}
+	if got := g.Cycles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected cycles %v, got %v", want, got)
+	}
+	if got := len(g.From(bc("a"))); got != 1 {
+		t.Errorf("expected one edge from a, got %d", got)
+	}
+
+	out := &bytes.Buffer{}
+	if err := g.WriteDOT(out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"BuildConfig/ns/loop" -> "ImageStreamTag/ns/loop:latest" [label="Output"];`) {
+		t.Errorf("unexpected DOT output:\n%s", out)
+	}
+}
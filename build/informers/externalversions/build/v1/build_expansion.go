@@ -0,0 +1,28 @@
+package v1
+
+import (
+	buildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// IndexedBuildLister registers BuildConfigIndex and BuildPhaseIndex on the shared informer of
+// informer, unless already present, and returns a lister that looks Builds up by build config and
+// phase through them. It must be called before the informer is started: a running informer rejects
+// new indexers and IndexedBuildLister returns that error, unless both indexes were registered before.
+func IndexedBuildLister(informer BuildInformer) (buildv1.BuildLister, error) {
+	sharedInformer := informer.Informer()
+	indexers := cache.Indexers{}
+	existing := sharedInformer.GetIndexer().GetIndexers()
+	if _, ok := existing[buildv1.BuildConfigIndex]; !ok {
+		indexers[buildv1.BuildConfigIndex] = buildv1.BuildConfigIndexFunc
+	}
+	if _, ok := existing[buildv1.BuildPhaseIndex]; !ok {
+		indexers[buildv1.BuildPhaseIndex] = buildv1.BuildPhaseIndexFunc
+	}
+	if len(indexers) > 0 {
+		if err := sharedInformer.AddIndexers(indexers); err != nil {
+			return nil, err
+		}
+	}
+	return buildv1.NewIndexedBuildLister(sharedInformer.GetIndexer()), nil
+}
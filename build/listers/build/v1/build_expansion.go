@@ -0,0 +1,259 @@
+package v1
+
+import (
+	fmt "fmt"
+	sort "sort"
+	strconv "strconv"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	labels "k8s.io/apimachinery/pkg/labels"
+	validation "k8s.io/apimachinery/pkg/util/validation"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+const (
+	// BuildConfigIndex is the name of the index of Builds by the namespace and name of the
+	// BuildConfig they were created from, computed by BuildConfigIndexFunc.
+	BuildConfigIndex = "build.openshift.io/buildconfig"
+	// BuildPhaseIndex is the name of the index of Builds by phase, computed by BuildPhaseIndexFunc.
+	BuildPhaseIndex = "build.openshift.io/phase"
+)
+
+// BuildConfigIndexFunc indexes a Build by <namespace>/<name> of the BuildConfig it was created from.
+// The name is taken from the build config annotation or, for builds without it, from the possibly
+// truncated build config label, the deprecated build config label or the status of the build.
+func BuildConfigIndexFunc(obj interface{}) ([]string, error) {
+	build, ok := obj.(*buildv1.Build)
+	if !ok {
+		return nil, nil
+	}
+	if name := buildConfigNameOf(build); len(name) > 0 {
+		return []string{build.Namespace + "/" + name}, nil
+	}
+	return nil, nil
+}
+
+// buildConfigNameOf returns the name of the BuildConfig build was created from, empty if it was not
+// created from a BuildConfig. The name read from the build config label may be truncated.
+func buildConfigNameOf(build *buildv1.Build) string {
+	if name, ok := build.Annotations[buildv1.BuildConfigAnnotation]; ok {
+		return name
+	}
+	if name, ok := build.Labels[buildv1.BuildConfigLabel]; ok {
+		return name
+	}
+	if name, ok := build.Labels[buildv1.BuildConfigLabelDeprecated]; ok {
+		return name
+	}
+	if build.Status.Config != nil {
+		return build.Status.Config.Name
+	}
+	return ""
+}
+
+// BuildPhaseIndexFunc indexes a Build by its phase, both cluster wide as <phase> and within its
+// namespace as <namespace>/<phase>.
+func BuildPhaseIndexFunc(obj interface{}) ([]string, error) {
+	build, ok := obj.(*buildv1.Build)
+	if !ok {
+		return nil, nil
+	}
+	return []string{string(build.Status.Phase), build.Namespace + "/" + string(build.Status.Phase)}, nil
+}
+
+// BuildListerExpansion allows custom methods to be added to
+// BuildLister.
+type BuildListerExpansion interface {
+	// ByPhase lists all Builds in the indexer that are in the given phase. It lists all Builds
+	// unless the lister was created by NewIndexedBuildLister with BuildPhaseIndex registered.
+	// Objects returned here must be treated as read-only.
+	ByPhase(phase buildv1.BuildPhase) ([]*buildv1.Build, error)
+}
+
+// BuildNamespaceListerExpansion allows custom methods to be added to
+// BuildNamespaceLister.
+type BuildNamespaceListerExpansion interface {
+	// ListForBuildConfig lists the Builds created from the named BuildConfig, ordered by build number.
+	// It lists all Builds of the namespace unless the lister was created by NewIndexedBuildLister with
+	// BuildConfigIndex registered.
+	// Objects returned here must be treated as read-only.
+	ListForBuildConfig(buildConfigName string) ([]*buildv1.Build, error)
+	// LatestForBuildConfig retrieves the Build with the highest build number created from the named BuildConfig.
+	// Objects returned here must be treated as read-only.
+	LatestForBuildConfig(buildConfigName string) (*buildv1.Build, error)
+	// ByPhase lists the Builds for a given namespace that are in the given phase. It lists all Builds
+	// of the namespace unless the lister was created by NewIndexedBuildLister with BuildPhaseIndex
+	// registered.
+	// Objects returned here must be treated as read-only.
+	ByPhase(phase buildv1.BuildPhase) ([]*buildv1.Build, error)
+}
+
+func (s *buildLister) ByPhase(phase buildv1.BuildPhase) ([]*buildv1.Build, error) {
+	builds, err := s.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return filterByPhase(builds, phase), nil
+}
+
+func (s buildNamespaceLister) ByPhase(phase buildv1.BuildPhase) ([]*buildv1.Build, error) {
+	builds, err := s.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return filterByPhase(builds, phase), nil
+}
+
+func (s buildNamespaceLister) ListForBuildConfig(buildConfigName string) ([]*buildv1.Build, error) {
+	builds, err := s.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*buildv1.Build, 0, len(builds))
+	for _, build := range builds {
+		if createdFrom(build, buildConfigName) {
+			ret = append(ret, build)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return buildNumberLess(ret[i], ret[j])
+	})
+	return ret, nil
+}
+
+// createdFrom returns true if build was created from the named BuildConfig. The label value is
+// truncated for long names, the annotation holds the full name.
+func createdFrom(build *buildv1.Build, buildConfigName string) bool {
+	name := buildConfigNameOf(build)
+	if name == buildConfigName {
+		return true
+	}
+	_, annotated := build.Annotations[buildv1.BuildConfigAnnotation]
+	return !annotated && len(buildConfigName) > validation.LabelValueMaxLength && name == buildConfigName[:validation.LabelValueMaxLength]
+}
+
+func (s buildNamespaceLister) LatestForBuildConfig(buildConfigName string) (*buildv1.Build, error) {
+	return latestForBuildConfig(s, buildConfigName)
+}
+
+func latestForBuildConfig(s BuildNamespaceLister, buildConfigName string) (*buildv1.Build, error) {
+	builds, err := s.ListForBuildConfig(buildConfigName)
+	if err != nil {
+		return nil, err
+	}
+	if len(builds) == 0 {
+		return nil, errors.NewNotFound(buildv1.Resource("build"), fmt.Sprintf("latest build of buildconfig %s", buildConfigName))
+	}
+	return builds[len(builds)-1], nil
+}
+
+// indexedBuildLister looks Builds up through BuildConfigIndex and BuildPhaseIndex.
+type indexedBuildLister struct {
+	BuildLister
+	indexer cache.Indexer
+}
+
+// NewIndexedBuildLister returns a new BuildLister whose lookups by build config and phase use
+// BuildConfigIndex and BuildPhaseIndex of indexer. Lookups fall back to listing all Builds if
+// indexer does not have the index. The indexes of a shared informer must be added before it is
+// started, see IndexedBuildLister of the informers package.
+func NewIndexedBuildLister(indexer cache.Indexer) BuildLister {
+	return &indexedBuildLister{BuildLister: NewBuildLister(indexer), indexer: indexer}
+}
+
+func (s *indexedBuildLister) ByPhase(phase buildv1.BuildPhase) ([]*buildv1.Build, error) {
+	if !hasIndex(s.indexer, BuildPhaseIndex) {
+		return s.BuildLister.ByPhase(phase)
+	}
+	return byIndex(s.indexer, BuildPhaseIndex, string(phase))
+}
+
+func (s *indexedBuildLister) Builds(namespace string) BuildNamespaceLister {
+	return indexedBuildNamespaceLister{BuildNamespaceLister: s.BuildLister.Builds(namespace), indexer: s.indexer, namespace: namespace}
+}
+
+// indexedBuildNamespaceLister looks Builds of a namespace up through BuildConfigIndex and BuildPhaseIndex.
+type indexedBuildNamespaceLister struct {
+	BuildNamespaceLister
+	indexer   cache.Indexer
+	namespace string
+}
+
+func (s indexedBuildNamespaceLister) ByPhase(phase buildv1.BuildPhase) ([]*buildv1.Build, error) {
+	if !hasIndex(s.indexer, BuildPhaseIndex) {
+		return s.BuildNamespaceLister.ByPhase(phase)
+	}
+	return byIndex(s.indexer, BuildPhaseIndex, s.namespace+"/"+string(phase))
+}
+
+func (s indexedBuildNamespaceLister) ListForBuildConfig(buildConfigName string) ([]*buildv1.Build, error) {
+	if !hasIndex(s.indexer, BuildConfigIndex) {
+		return s.BuildNamespaceLister.ListForBuildConfig(buildConfigName)
+	}
+	ret, err := byIndex(s.indexer, BuildConfigIndex, s.namespace+"/"+buildConfigName)
+	if err != nil {
+		return nil, err
+	}
+	// Builds of long build config names without the annotation are indexed by the truncated label.
+	if len(buildConfigName) > validation.LabelValueMaxLength {
+		truncated, err := byIndex(s.indexer, BuildConfigIndex, s.namespace+"/"+buildConfigName[:validation.LabelValueMaxLength])
+		if err != nil {
+			return nil, err
+		}
+		for _, build := range truncated {
+			if _, ok := build.Annotations[buildv1.BuildConfigAnnotation]; !ok {
+				ret = append(ret, build)
+			}
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return buildNumberLess(ret[i], ret[j])
+	})
+	return ret, nil
+}
+
+func (s indexedBuildNamespaceLister) LatestForBuildConfig(buildConfigName string) (*buildv1.Build, error) {
+	return latestForBuildConfig(s, buildConfigName)
+}
+
+func hasIndex(indexer cache.Indexer, name string) bool {
+	_, ok := indexer.GetIndexers()[name]
+	return ok
+}
+
+func byIndex(indexer cache.Indexer, name, value string) ([]*buildv1.Build, error) {
+	objs, err := indexer.ByIndex(name, value)
+	if err != nil {
+		return nil, err
+	}
+	builds := make([]*buildv1.Build, 0, len(objs))
+	for _, obj := range objs {
+		if build, ok := obj.(*buildv1.Build); ok {
+			builds = append(builds, build)
+		}
+	}
+	return builds, nil
+}
+
+func filterByPhase(builds []*buildv1.Build, phase buildv1.BuildPhase) []*buildv1.Build {
+	var ret []*buildv1.Build
+	for _, build := range builds {
+		if build.Status.Phase == phase {
+			ret = append(ret, build)
+		}
+	}
+	return ret
+}
+
+// buildNumberLess orders builds by their build number annotation, falling back to the creation
+// timestamp for builds without a valid one.
+func buildNumberLess(a, b *buildv1.Build) bool {
+	numberA, errA := strconv.ParseInt(a.Annotations[buildv1.BuildNumberAnnotation], 10, 64)
+	numberB, errB := strconv.ParseInt(b.Annotations[buildv1.BuildNumberAnnotation], 10, 64)
+	if errA == nil && errB == nil && numberA != numberB {
+		return numberA < numberB
+	}
+	return a.CreationTimestamp.Before(&b.CreationTimestamp)
+}
@@ -0,0 +1,63 @@
+package v1
+
+import (
+	reflect "reflect"
+	strings "strings"
+	testing "testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+func TestListForBuildConfig(t *testing.T) {
+	long := strings.Repeat("a", 70)
+	builds := []*buildv1.Build{
+		{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Labels: map[string]string{buildv1.BuildConfigLabelDeprecated: "app"}, Annotations: map[string]string{buildv1.BuildNumberAnnotation: "1"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "app-2", Labels: map[string]string{buildv1.BuildConfigLabel: "app"}, Annotations: map[string]string{buildv1.BuildNumberAnnotation: "2"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "app-3", Annotations: map[string]string{buildv1.BuildConfigAnnotation: "app", buildv1.BuildNumberAnnotation: "3"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "app-4", Annotations: map[string]string{buildv1.BuildNumberAnnotation: "4"}}, Status: buildv1.BuildStatus{Config: &corev1.ObjectReference{Name: "app"}}},
+		// the label matches, but the annotation names another build config
+		{ObjectMeta: metav1.ObjectMeta{Name: "other-1", Labels: map[string]string{buildv1.BuildConfigLabel: "app"}, Annotations: map[string]string{buildv1.BuildConfigAnnotation: "other"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "long-1", Labels: map[string]string{buildv1.BuildConfigLabel: long[:63]}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "manual"}},
+	}
+	tests := []struct {
+		config string
+		want   []string
+	}{
+		{config: "app", want: []string{"app-1", "app-2", "app-3", "app-4"}},
+		{config: "other", want: []string{"other-1"}},
+		{config: long, want: []string{"long-1"}},
+		{config: "missing"},
+	}
+
+	plain := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	indexed := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc, BuildConfigIndex: BuildConfigIndexFunc})
+	for _, build := range builds {
+		build.Namespace = "ns"
+		if err := plain.Add(build); err != nil {
+			t.Fatal(err)
+		}
+		if err := indexed.Add(build); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listers := map[string]BuildLister{"plain": NewBuildLister(plain), "indexed": NewIndexedBuildLister(indexed)}
+	for kind, lister := range listers {
+		for _, test := range tests {
+			list, err := lister.Builds("ns").ListForBuildConfig(test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, build := range list {
+				got = append(got, build.Name)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s lister, build config %.10s: expected %v, got %v", kind, test.config, test.want, got)
+			}
+		}
+	}
+}
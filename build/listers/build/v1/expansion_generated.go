@@ -2,14 +2,6 @@
 
 package v1
 
-// BuildListerExpansion allows custom methods to be added to
-// BuildLister.
-type BuildListerExpansion interface{}
-
-// BuildNamespaceListerExpansion allows custom methods to be added to
-// BuildNamespaceLister.
-type BuildNamespaceListerExpansion interface{}
-
 // BuildConfigListerExpansion allows custom methods to be added to
 // BuildConfigLister.
 type BuildConfigListerExpansion interface{}
@@ -0,0 +1,137 @@
+// Package metrics exports Prometheus metrics about the builds observed by a build informer.
+package metrics
+
+import (
+	buildv1 "github.com/openshift/api/build/v1"
+	informersbuildv1 "github.com/openshift/client-go/build/informers/externalversions/build/v1"
+	listersbuildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	waiter "github.com/openshift/client-go/build/waiter"
+	prometheus "github.com/prometheus/client_golang/prometheus"
+	labels "k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+const (
+	namespace = "openshift"
+	subsystem = "build"
+)
+
+// Collector is a prometheus.Collector reporting the number of builds by phase, reason and
+// strategy, and the durations of finished builds and their stages.
+//
+// Durations are recorded when the informer observes a build finishing, so builds that finished
+// before the collector was created are counted but their durations are not recorded.
+type Collector struct {
+	lister listersbuildv1.BuildLister
+
+	builds        *prometheus.Desc
+	duration      *prometheus.HistogramVec
+	stageDuration *prometheus.HistogramVec
+}
+
+var _ prometheus.Collector = &Collector{}
+
+// NewCollector returns a Collector fed by informer. The informer must be started by the caller,
+// and the collector registered with a prometheus.Registerer.
+func NewCollector(informer informersbuildv1.BuildInformer) (*Collector, error) {
+	c := &Collector{
+		lister: informer.Lister(),
+		builds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "total"),
+			"Counts builds by phase, reason and strategy",
+			[]string{"phase", "reason", "strategy"},
+			nil,
+		),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "duration_seconds",
+			Help:      "Duration of finished builds from start to completion",
+			Buckets:   prometheus.ExponentialBuckets(10, 2, 10),
+		}, []string{"phase", "strategy"}),
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "stage_duration_seconds",
+			Help:      "Duration of the stages of finished builds",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+		}, []string{"stage", "strategy"}),
+	}
+
+	_, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldBuild, ok := oldObj.(*buildv1.Build)
+			if !ok {
+				return
+			}
+			newBuild, ok := newObj.(*buildv1.Build)
+			if !ok {
+				return
+			}
+			// only the transition into a terminal phase is recorded, resyncs are ignored
+			if !waiter.IsTerminal(oldBuild.Status.Phase) && waiter.IsTerminal(newBuild.Status.Phase) {
+				c.observe(newBuild)
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.builds
+	c.duration.Describe(ch)
+	c.stageDuration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	builds, err := c.lister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+	} else {
+		counts := map[[3]string]int{}
+		for _, build := range builds {
+			counts[[3]string{string(build.Status.Phase), string(build.Status.Reason), strategyType(build)}]++
+		}
+		for key, count := range counts {
+			ch <- prometheus.MustNewConstMetric(c.builds, prometheus.GaugeValue, float64(count), key[0], key[1], key[2])
+		}
+	}
+	c.duration.Collect(ch)
+	c.stageDuration.Collect(ch)
+}
+
+// observe records the durations of a finished build.
+func (c *Collector) observe(build *buildv1.Build) {
+	strategy := strategyType(build)
+	if start, end := build.Status.StartTimestamp, build.Status.CompletionTimestamp; start != nil && end != nil {
+		c.duration.WithLabelValues(string(build.Status.Phase), strategy).Observe(end.Sub(start.Time).Seconds())
+	}
+	for _, stage := range build.Status.Stages {
+		c.stageDuration.WithLabelValues(string(stage.Name), strategy).Observe(float64(stage.DurationMilliseconds) / 1000)
+	}
+}
+
+// strategyType returns the strategy of build, derived from the strategy parameters when the
+// type is not set.
+func strategyType(build *buildv1.Build) string {
+	strategy := build.Spec.Strategy
+	switch {
+	case len(strategy.Type) > 0:
+		return string(strategy.Type)
+	case strategy.DockerStrategy != nil:
+		return string(buildv1.DockerBuildStrategyType)
+	case strategy.SourceStrategy != nil:
+		return string(buildv1.SourceBuildStrategyType)
+	case strategy.CustomStrategy != nil:
+		return string(buildv1.CustomBuildStrategyType)
+	case strategy.JenkinsPipelineStrategy != nil:
+		return string(buildv1.JenkinsPipelineBuildStrategyType)
+	}
+	return ""
+}
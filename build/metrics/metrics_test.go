@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	context "context"
+	sync "sync"
+	testing "testing"
+	time "time"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	fake "github.com/openshift/client-go/build/clientset/versioned/fake"
+	externalversions "github.com/openshift/client-go/build/informers/externalversions"
+	prometheus "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	wait "k8s.io/apimachinery/pkg/util/wait"
+	watch "k8s.io/apimachinery/pkg/watch"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestCollector(t *testing.T) {
+	start := metav1.NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	client := fake.NewSimpleClientset(
+		&buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app-1"},
+			Spec:       buildv1.BuildSpec{CommonSpec: buildv1.CommonSpec{Strategy: buildv1.BuildStrategy{SourceStrategy: &buildv1.SourceBuildStrategy{}}}},
+			Status:     buildv1.BuildStatus{Phase: buildv1.BuildPhaseRunning, StartTimestamp: &start},
+		},
+		// finished before the collector was created: counted, but without durations
+		&buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "tools-1"},
+			Spec:       buildv1.BuildSpec{CommonSpec: buildv1.CommonSpec{Strategy: buildv1.BuildStrategy{Type: buildv1.DockerBuildStrategyType}}},
+			Status:     buildv1.BuildStatus{Phase: buildv1.BuildPhaseFailed, Reason: buildv1.StatusReasonGenericBuildFailed},
+		},
+	)
+	watching := make(chan struct{})
+	var once sync.Once
+	client.PrependWatchReactor("builds", func(clienttesting.Action) (bool, watch.Interface, error) {
+		once.Do(func() { close(watching) })
+		return false, nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+	defer cancel()
+	factory := externalversions.NewSharedInformerFactory(client, 0)
+	collector, err := NewCollector(factory.Build().V1().Builds())
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	<-watching
+
+	build, err := client.BuildV1().Builds("ns").Get(ctx, "app-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := metav1.NewTime(start.Add(90 * time.Second))
+	build.Status.Phase = buildv1.BuildPhaseComplete
+	build.Status.CompletionTimestamp = &end
+	build.Status.Stages = []buildv1.StageInfo{
+		{Name: buildv1.StageFetchInputs, DurationMilliseconds: 2000},
+		{Name: buildv1.StageBuild, DurationMilliseconds: 80000},
+	}
+	if _, err := client.BuildV1().Builds("ns").Update(ctx, build, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var families map[string]*dto.MetricFamily
+	err = wait.PollUntilContextCancel(ctx, 10*time.Millisecond, true, func(context.Context) (bool, error) {
+		gathered, err := registry.Gather()
+		if err != nil {
+			return false, err
+		}
+		families = map[string]*dto.MetricFamily{}
+		for _, family := range gathered {
+			families[family.GetName()] = family
+		}
+		return families["openshift_build_duration_seconds"] != nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	totals := map[string]float64{}
+	for _, metric := range families["openshift_build_total"].GetMetric() {
+		totals[labelValues(metric)] = metric.GetGauge().GetValue()
+	}
+	want := map[string]float64{
+		"phase=Complete,reason=,strategy=Source":                 1,
+		"phase=Failed,reason=GenericBuildFailed,strategy=Docker": 1,
+	}
+	for labels, count := range want {
+		if totals[labels] != count {
+			t.Errorf("expected %v builds with %s, got %v", count, labels, totals)
+		}
+	}
+
+	durations := families["openshift_build_duration_seconds"].GetMetric()
+	if len(durations) != 1 || durations[0].GetHistogram().GetSampleCount() != 1 || durations[0].GetHistogram().GetSampleSum() != 90 {
+		t.Errorf("expected a single 90s Source build duration, got %v", durations)
+	}
+	stages := map[string]float64{}
+	for _, metric := range families["openshift_build_stage_duration_seconds"].GetMetric() {
+		stages[labelValues(metric)] = metric.GetHistogram().GetSampleSum()
+	}
+	if stages["stage=Build,strategy=Source"] != 80 || stages["stage=FetchInputs,strategy=Source"] != 2 {
+		t.Errorf("unexpected stage durations %v", stages)
+	}
+}
+
+func TestStrategyType(t *testing.T) {
+	tests := map[string]buildv1.BuildStrategy{
+		"Custom":          {Type: buildv1.CustomBuildStrategyType, SourceStrategy: &buildv1.SourceBuildStrategy{}},
+		"Docker":          {DockerStrategy: &buildv1.DockerBuildStrategy{}},
+		"Source":          {SourceStrategy: &buildv1.SourceBuildStrategy{}},
+		"JenkinsPipeline": {JenkinsPipelineStrategy: &buildv1.JenkinsPipelineBuildStrategy{}},
+		"":                {},
+	}
+	for want, strategy := range tests {
+		build := &buildv1.Build{Spec: buildv1.BuildSpec{CommonSpec: buildv1.CommonSpec{Strategy: strategy}}}
+		if got := strategyType(build); got != want {
+			t.Errorf("expected strategy %q, got %q", want, got)
+		}
+	}
+}
+
+// labelValues renders the labels of metric as name=value pairs in label order.
+func labelValues(metric *dto.Metric) string {
+	var rendered string
+	for i, label := range metric.GetLabel() {
+		if i > 0 {
+			rendered += ","
+		}
+		rendered += label.GetName() + "=" + label.GetValue()
+	}
+	return rendered
+}
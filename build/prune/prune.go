@@ -0,0 +1,253 @@
+// Package prune deletes old builds, honoring the history limits of their build configs.
+package prune
+
+import (
+	context "context"
+	fmt "fmt"
+	io "io"
+	sort "sort"
+	tabwriter "text/tabwriter"
+	time "time"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	typedbuildv1 "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
+	listersbuildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	flowcontrol "k8s.io/client-go/util/flowcontrol"
+)
+
+// Reason explains why a build is a prune candidate.
+type Reason string
+
+const (
+	// ReasonOrphan means the build config that created the build no longer exists.
+	ReasonOrphan Reason = "Orphan"
+	// ReasonCompleteHistory means the build exceeds the number of complete builds to keep.
+	ReasonCompleteHistory Reason = "CompleteHistoryLimit"
+	// ReasonFailedHistory means the build exceeds the number of failed builds to keep.
+	ReasonFailedHistory Reason = "FailedHistoryLimit"
+)
+
+// Options configures which builds are pruned.
+type Options struct {
+	// Namespace restricts pruning to a single namespace, all namespaces when empty.
+	Namespace string
+	// KeepYoungerThan protects builds created more recently than this duration.
+	KeepYoungerThan time.Duration
+	// Orphans prunes finished builds whose build config no longer exists.
+	Orphans bool
+	// KeepComplete is the number of complete builds kept per build config, unless the build
+	// config sets SuccessfulBuildsHistoryLimit.
+	KeepComplete int
+	// KeepFailed is the number of failed, errored and cancelled builds kept per build config,
+	// unless the build config sets FailedBuildsHistoryLimit.
+	KeepFailed int
+	// QPS and Burst limit the rate of delete requests.
+	QPS   float32
+	Burst int
+}
+
+// DefaultOptions returns the options oc adm prune builds uses by default.
+func DefaultOptions() Options {
+	return Options{
+		KeepYoungerThan: 60 * time.Minute,
+		Orphans:         false,
+		KeepComplete:    5,
+		KeepFailed:      1,
+		QPS:             10,
+		Burst:           10,
+	}
+}
+
+// Candidate is a build selected for pruning.
+type Candidate struct {
+	Build  *buildv1.Build
+	Reason Reason
+}
+
+// Pruner computes and deletes prune candidates.
+type Pruner struct {
+	client  typedbuildv1.BuildsGetter
+	builds  listersbuildv1.BuildLister
+	configs listersbuildv1.BuildConfigLister
+	options Options
+	now     func() time.Time
+}
+
+// NewPruner returns a Pruner that reads builds and build configs through the given listers and
+// deletes builds through client.
+func NewPruner(client typedbuildv1.BuildsGetter, builds listersbuildv1.BuildLister, configs listersbuildv1.BuildConfigLister, options Options) *Pruner {
+	return &Pruner{
+		client:  client,
+		builds:  builds,
+		configs: configs,
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// Candidates returns the builds that should be pruned, ordered by namespace and name.
+func (p *Pruner) Candidates() ([]Candidate, error) {
+	var builds []*buildv1.Build
+	var err error
+	if len(p.options.Namespace) > 0 {
+		builds, err = p.builds.Builds(p.options.Namespace).List(labels.Everything())
+	} else {
+		builds, err = p.builds.List(labels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byConfig := map[configKey][]*buildv1.Build{}
+	for _, build := range builds {
+		key := configKey{namespace: build.Namespace, name: ConfigNameForBuild(build)}
+		byConfig[key] = append(byConfig[key], build)
+	}
+
+	var candidates []Candidate
+	for key, builds := range byConfig {
+		// builds that were not created from a build config are left alone
+		if len(key.name) == 0 {
+			continue
+		}
+		config, err := p.configs.BuildConfigs(key.namespace).Get(key.name)
+		switch {
+		case errors.IsNotFound(err):
+			config = nil
+		case err != nil:
+			return nil, err
+		}
+		candidates = append(candidates, p.candidatesFor(config, builds)...)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Build.Namespace != candidates[j].Build.Namespace {
+			return candidates[i].Build.Namespace < candidates[j].Build.Namespace
+		}
+		return candidates[i].Build.Name < candidates[j].Build.Name
+	})
+	return candidates, nil
+}
+
+// candidatesFor returns the prune candidates among builds, all created from config, which is nil
+// if the build config no longer exists.
+func (p *Pruner) candidatesFor(config *buildv1.BuildConfig, builds []*buildv1.Build) []Candidate {
+	// newest first, so that the builds beyond the history limits are the oldest ones
+	sort.Slice(builds, func(i, j int) bool {
+		return builds[j].CreationTimestamp.Before(&builds[i].CreationTimestamp)
+	})
+
+	keepComplete, keepFailed := p.options.KeepComplete, p.options.KeepFailed
+	if config != nil {
+		if config.Spec.SuccessfulBuildsHistoryLimit != nil {
+			keepComplete = int(*config.Spec.SuccessfulBuildsHistoryLimit)
+		}
+		if config.Spec.FailedBuildsHistoryLimit != nil {
+			keepFailed = int(*config.Spec.FailedBuildsHistoryLimit)
+		}
+	}
+
+	var candidates []Candidate
+	complete, failed := 0, 0
+	for _, build := range builds {
+		var reason Reason
+		switch build.Status.Phase {
+		case buildv1.BuildPhaseComplete:
+			complete++
+			if complete > keepComplete {
+				reason = ReasonCompleteHistory
+			}
+		case buildv1.BuildPhaseFailed, buildv1.BuildPhaseError, buildv1.BuildPhaseCancelled:
+			failed++
+			if failed > keepFailed {
+				reason = ReasonFailedHistory
+			}
+		default:
+			// builds that have not finished are never pruned
+			continue
+		}
+		if config == nil {
+			if !p.options.Orphans {
+				continue
+			}
+			reason = ReasonOrphan
+		}
+		if len(reason) == 0 || p.isYoung(build) {
+			continue
+		}
+		candidates = append(candidates, Candidate{Build: build, Reason: reason})
+	}
+	return candidates
+}
+
+func (p *Pruner) isYoung(build *buildv1.Build) bool {
+	return p.now().Sub(build.CreationTimestamp.Time) < p.options.KeepYoungerThan
+}
+
+// Prune deletes the prune candidates, or only reports them when dryRun is true. Every candidate
+// is written to out, which may be nil. It returns the candidates and the first deletion error.
+func (p *Pruner) Prune(ctx context.Context, dryRun bool, out io.Writer) ([]Candidate, error) {
+	candidates, err := p.Candidates()
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = io.Discard
+	}
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tREASON")
+
+	limiter := flowcontrol.NewFakeAlwaysRateLimiter()
+	if p.options.QPS > 0 {
+		burst := p.options.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		limiter = flowcontrol.NewTokenBucketRateLimiter(p.options.QPS, burst)
+	}
+	defer limiter.Stop()
+
+	var firstErr error
+	for _, candidate := range candidates {
+		build := candidate.Build
+		fmt.Fprintf(w, "%s\t%s\t%s\n", build.Namespace, build.Name, candidate.Reason)
+		if dryRun {
+			continue
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return candidates, err
+		}
+		err := p.client.Builds(build.Namespace).Delete(ctx, build.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) && firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete build %s/%s: %w", build.Namespace, build.Name, err)
+		}
+	}
+	return candidates, firstErr
+}
+
+// ConfigNameForBuild returns the name of the build config that created build, empty if the build
+// was not created from a build config.
+func ConfigNameForBuild(build *buildv1.Build) string {
+	if name, ok := build.Annotations[buildv1.BuildConfigAnnotation]; ok {
+		return name
+	}
+	if name, ok := build.Labels[buildv1.BuildConfigLabel]; ok {
+		return name
+	}
+	if name, ok := build.Labels[buildv1.BuildConfigLabelDeprecated]; ok {
+		return name
+	}
+	if build.Status.Config != nil {
+		return build.Status.Config.Name
+	}
+	return ""
+}
+
+type configKey struct {
+	namespace string
+	name      string
+}
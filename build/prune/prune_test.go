@@ -0,0 +1,219 @@
+package prune
+
+import (
+	bytes "bytes"
+	context "context"
+	reflect "reflect"
+	strings "strings"
+	testing "testing"
+	time "time"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	fake "github.com/openshift/client-go/build/clientset/versioned/fake"
+	listersbuildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cache "k8s.io/client-go/tools/cache"
+	ptr "k8s.io/utils/ptr"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// row describes a build in namespace "ns": its name, how it refers to its build config, its phase
+// and how long ago it was created.
+type row struct {
+	name   string
+	config string
+	// link is how the build refers to config: "annotation" (the default), "label",
+	// "deprecated-label" or "status".
+	link  string
+	phase buildv1.BuildPhase
+	age   time.Duration
+}
+
+func (r row) build() *buildv1.Build {
+	build := &buildv1.Build{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: r.name, CreationTimestamp: metav1.NewTime(now.Add(-r.age))},
+		Status:     buildv1.BuildStatus{Phase: r.phase},
+	}
+	switch {
+	case len(r.config) == 0:
+	case r.link == "label":
+		build.Labels = map[string]string{buildv1.BuildConfigLabel: r.config}
+	case r.link == "deprecated-label":
+		build.Labels = map[string]string{buildv1.BuildConfigLabelDeprecated: r.config}
+	case r.link == "status":
+		build.Status.Config = &corev1.ObjectReference{Name: r.config}
+	default:
+		build.Annotations = map[string]string{buildv1.BuildConfigAnnotation: r.config}
+	}
+	return build
+}
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		configs []*buildv1.BuildConfig
+		rows    []row
+		want    map[string]Reason
+	}{
+		{
+			name:    "complete history",
+			options: Options{KeepComplete: 2, KeepFailed: 1},
+			configs: []*buildv1.BuildConfig{config("app", nil, nil)},
+			rows: []row{
+				{name: "app-1", config: "app", phase: buildv1.BuildPhaseComplete, age: 4 * time.Hour},
+				{name: "app-2", config: "app", phase: buildv1.BuildPhaseComplete, age: 3 * time.Hour},
+				{name: "app-3", config: "app", phase: buildv1.BuildPhaseComplete, age: 2 * time.Hour},
+				{name: "app-4", config: "app", phase: buildv1.BuildPhaseRunning, age: 5 * time.Hour},
+			},
+			want: map[string]Reason{"app-1": ReasonCompleteHistory},
+		},
+		{
+			name:    "failed history counts errors and cancellations",
+			options: Options{KeepComplete: 5, KeepFailed: 1},
+			configs: []*buildv1.BuildConfig{config("app", nil, nil)},
+			rows: []row{
+				{name: "app-1", config: "app", phase: buildv1.BuildPhaseFailed, age: 4 * time.Hour},
+				{name: "app-2", config: "app", phase: buildv1.BuildPhaseError, age: 3 * time.Hour},
+				{name: "app-3", config: "app", phase: buildv1.BuildPhaseCancelled, age: 2 * time.Hour},
+				{name: "app-4", config: "app", phase: buildv1.BuildPhaseComplete, age: 1 * time.Hour},
+			},
+			want: map[string]Reason{"app-1": ReasonFailedHistory, "app-2": ReasonFailedHistory},
+		},
+		{
+			name:    "build config limits override the options",
+			options: Options{KeepComplete: 5, KeepFailed: 5},
+			configs: []*buildv1.BuildConfig{config("app", ptr.To[int32](1), ptr.To[int32](0))},
+			rows: []row{
+				{name: "app-1", config: "app", phase: buildv1.BuildPhaseComplete, age: 4 * time.Hour},
+				{name: "app-2", config: "app", phase: buildv1.BuildPhaseFailed, age: 3 * time.Hour},
+				{name: "app-3", config: "app", phase: buildv1.BuildPhaseComplete, age: 2 * time.Hour},
+			},
+			want: map[string]Reason{"app-1": ReasonCompleteHistory, "app-2": ReasonFailedHistory},
+		},
+		{
+			name:    "young builds are kept",
+			options: Options{KeepYoungerThan: time.Hour},
+			configs: []*buildv1.BuildConfig{config("app", nil, nil)},
+			rows: []row{
+				{name: "app-1", config: "app", phase: buildv1.BuildPhaseComplete, age: 2 * time.Hour},
+				{name: "app-2", config: "app", phase: buildv1.BuildPhaseComplete, age: 30 * time.Minute},
+			},
+			want: map[string]Reason{"app-1": ReasonCompleteHistory},
+		},
+		{
+			name:    "orphans are kept unless requested",
+			options: Options{KeepComplete: 5, KeepFailed: 5},
+			rows: []row{
+				{name: "gone-1", config: "gone", phase: buildv1.BuildPhaseComplete, age: 2 * time.Hour},
+			},
+			want: map[string]Reason{},
+		},
+		{
+			name:    "orphans regardless of history",
+			options: Options{KeepComplete: 5, KeepFailed: 5, Orphans: true},
+			rows: []row{
+				{name: "gone-1", config: "gone", phase: buildv1.BuildPhaseComplete, age: 2 * time.Hour},
+				{name: "gone-2", config: "gone", phase: buildv1.BuildPhaseFailed, age: 2 * time.Hour},
+				{name: "gone-3", config: "gone", phase: buildv1.BuildPhasePending, age: 2 * time.Hour},
+			},
+			want: map[string]Reason{"gone-1": ReasonOrphan, "gone-2": ReasonOrphan},
+		},
+		{
+			name:    "builds without a build config are left alone",
+			options: Options{Orphans: true},
+			rows: []row{
+				{name: "manual", phase: buildv1.BuildPhaseComplete, age: 2 * time.Hour},
+			},
+			want: map[string]Reason{},
+		},
+		{
+			name:    "every way of referring to the build config",
+			options: Options{KeepComplete: 1},
+			configs: []*buildv1.BuildConfig{config("app", nil, nil)},
+			rows: []row{
+				{name: "app-1", config: "app", link: "deprecated-label", phase: buildv1.BuildPhaseComplete, age: 5 * time.Hour},
+				{name: "app-2", config: "app", link: "status", phase: buildv1.BuildPhaseComplete, age: 4 * time.Hour},
+				{name: "app-3", config: "app", link: "label", phase: buildv1.BuildPhaseComplete, age: 3 * time.Hour},
+				{name: "app-4", config: "app", phase: buildv1.BuildPhaseComplete, age: 2 * time.Hour},
+			},
+			want: map[string]Reason{"app-1": ReasonCompleteHistory, "app-2": ReasonCompleteHistory, "app-3": ReasonCompleteHistory},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pruner, _ := newPruner(t, test.options, test.configs, test.rows)
+			candidates, err := pruner.Candidates()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]Reason{}
+			for _, candidate := range candidates {
+				got[candidate.Build.Name] = candidate.Reason
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	rows := []row{
+		{name: "app-1", config: "app", phase: buildv1.BuildPhaseComplete, age: 3 * time.Hour},
+		{name: "app-2", config: "app", phase: buildv1.BuildPhaseComplete, age: 2 * time.Hour},
+	}
+	for _, dryRun := range []bool{true, false} {
+		pruner, client := newPruner(t, Options{KeepComplete: 1}, []*buildv1.BuildConfig{config("app", nil, nil)}, rows)
+		out := &bytes.Buffer{}
+		candidates, err := pruner.Prune(context.TODO(), dryRun, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(candidates) != 1 || !strings.Contains(out.String(), "app-1") {
+			t.Fatalf("dry run %t: unexpected candidates %v, output %q", dryRun, candidates, out.String())
+		}
+		var deletes int
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "delete" {
+				deletes++
+			}
+		}
+		if want := map[bool]int{true: 0, false: 1}[dryRun]; deletes != want {
+			t.Errorf("dry run %t: expected %d deletions, got %d", dryRun, want, deletes)
+		}
+	}
+}
+
+func config(name string, keepComplete, keepFailed *int32) *buildv1.BuildConfig {
+	return &buildv1.BuildConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+		Spec:       buildv1.BuildConfigSpec{SuccessfulBuildsHistoryLimit: keepComplete, FailedBuildsHistoryLimit: keepFailed},
+	}
+}
+
+func newPruner(t *testing.T, options Options, configs []*buildv1.BuildConfig, rows []row) (*Pruner, *fake.Clientset) {
+	t.Helper()
+	builds := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	client := fake.NewSimpleClientset()
+	for _, r := range rows {
+		build := r.build()
+		if err := builds.Add(build); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Tracker().Add(build); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buildConfigs := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, c := range configs {
+		if err := buildConfigs.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruner := NewPruner(client.BuildV1(), listersbuildv1.NewBuildLister(builds), listersbuildv1.NewBuildConfigLister(buildConfigs), options)
+	pruner.now = func() time.Time { return now }
+	return pruner, client
+}
@@ -0,0 +1,25 @@
+// Package strategy answers questions about build strategies that several build and image
+// packages share. It depends on API types only.
+package strategy
+
+import (
+	buildv1 "github.com/openshift/api/build/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BuilderImage returns the builder image of a build strategy, the image a source or custom build
+// runs or a docker build starts from, or nil if the strategy has none. Image change triggers
+// without an explicit source watch this image.
+func BuilderImage(strategy *buildv1.BuildStrategy) *corev1.ObjectReference {
+	switch {
+	case strategy == nil:
+		return nil
+	case strategy.SourceStrategy != nil:
+		return &strategy.SourceStrategy.From
+	case strategy.DockerStrategy != nil:
+		return strategy.DockerStrategy.From
+	case strategy.CustomStrategy != nil:
+		return &strategy.CustomStrategy.From
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// Package waiter blocks until builds reach a terminal phase, reporting their progress on the way.
+package waiter
+
+import (
+	context "context"
+	fmt "fmt"
+	time "time"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	typedbuildv1 "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
+	informersbuildv1 "github.com/openshift/client-go/build/informers/externalversions/build/v1"
+	listersbuildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	equality "k8s.io/apimachinery/pkg/api/equality"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fields "k8s.io/apimachinery/pkg/fields"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// Event reports an observed change of the phase or stage timing of a build.
+type Event struct {
+	// Build is the observed state of the build. It must be treated as read-only.
+	Build *buildv1.Build
+	// PreviousPhase is the phase reported by the prior event, empty for the first event.
+	PreviousPhase buildv1.BuildPhase
+	// Phase is the phase of Build.
+	Phase buildv1.BuildPhase
+	// Stages is the stage and step timing reported in the status of Build.
+	Stages []buildv1.StageInfo
+	// Time is when the change was observed.
+	Time time.Time
+}
+
+// BuildError is returned when a build ends in a phase other than Complete.
+type BuildError struct {
+	Namespace string
+	Name      string
+	Phase     buildv1.BuildPhase
+	Reason    buildv1.StatusReason
+	Message   string
+}
+
+func (e *BuildError) Error() string {
+	msg := fmt.Sprintf("build %s/%s %s", e.Namespace, e.Name, e.Phase)
+	if len(e.Reason) > 0 {
+		msg += fmt.Sprintf(": %s", e.Reason)
+	}
+	if len(e.Message) > 0 {
+		msg += fmt.Sprintf(": %s", e.Message)
+	}
+	return msg
+}
+
+// IsTerminal returns true if a build in the given phase will not change phase anymore.
+func IsTerminal(phase buildv1.BuildPhase) bool {
+	switch phase {
+	case buildv1.BuildPhaseComplete, buildv1.BuildPhaseFailed, buildv1.BuildPhaseError, buildv1.BuildPhaseCancelled:
+		return true
+	}
+	return false
+}
+
+// Waiter waits for builds to finish, either by watching them through the API server or by
+// observing a shared build informer.
+type Waiter struct {
+	client   typedbuildv1.BuildsGetter
+	informer cache.SharedIndexInformer
+	lister   listersbuildv1.BuildLister
+}
+
+// NewWaiter returns a Waiter that watches builds through client, resuming interrupted watches
+// from the last observed resource version.
+func NewWaiter(client typedbuildv1.BuildsGetter) *Waiter {
+	return &Waiter{client: client}
+}
+
+// NewInformerWaiter returns a Waiter that observes builds through informer. The informer must
+// be started by the caller.
+func NewInformerWaiter(informer informersbuildv1.BuildInformer) *Waiter {
+	return &Waiter{informer: informer.Informer(), lister: informer.Lister()}
+}
+
+// Wait blocks until the named build reaches a terminal phase and returns its final state. Every
+// phase transition and stage timing update is sent on events, which may be nil. A *BuildError is
+// returned if the build does not complete successfully.
+func (w *Waiter) Wait(ctx context.Context, namespace, name string, events chan<- Event) (*buildv1.Build, error) {
+	var last *buildv1.Build
+	observe := func(build *buildv1.Build) (bool, error) {
+		if last != nil && last.Status.Phase == build.Status.Phase && equality.Semantic.DeepEqual(last.Status.Stages, build.Status.Stages) {
+			return false, nil
+		}
+		event := Event{
+			Build:  build,
+			Phase:  build.Status.Phase,
+			Stages: build.Status.Stages,
+			Time:   time.Now(),
+		}
+		if last != nil {
+			event.PreviousPhase = last.Status.Phase
+		}
+		last = build
+		if events != nil {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return false, ctx.Err()
+			}
+		}
+		return IsTerminal(build.Status.Phase), nil
+	}
+
+	var err error
+	if w.informer != nil {
+		err = w.observeInformer(ctx, namespace, name, observe)
+	} else {
+		err = w.observeWatch(ctx, namespace, name, observe)
+	}
+	if err != nil {
+		return last, err
+	}
+	if last.Status.Phase != buildv1.BuildPhaseComplete {
+		return last, &BuildError{
+			Namespace: namespace,
+			Name:      name,
+			Phase:     last.Status.Phase,
+			Reason:    last.Status.Reason,
+			Message:   last.Status.Message,
+		}
+	}
+	return last, nil
+}
+
+// observeWatch passes every observed state of the named build to observe until it returns true.
+func (w *Waiter) observeWatch(ctx context.Context, namespace, name string, observe func(*buildv1.Build) (bool, error)) error {
+	builds := w.client.Builds(namespace)
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return builds.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return builds.Watch(ctx, options)
+		},
+	}
+	exists := func(store cache.Store) (bool, error) {
+		if _, ok, err := store.GetByKey(namespace + "/" + name); err != nil || !ok {
+			return false, errors.NewNotFound(buildv1.Resource("build"), name)
+		}
+		return false, nil
+	}
+
+	_, err := watchtools.UntilWithSync(ctx, lw, &buildv1.Build{}, exists, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("build %s/%s was deleted before it finished", namespace, name)
+		}
+		build, ok := event.Object.(*buildv1.Build)
+		if !ok {
+			return false, fmt.Errorf("unexpected object %T in build watch", event.Object)
+		}
+		return observe(build)
+	})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// observeInformer passes every state of the named build observed by the informer to observe
+// until it returns true. A build missing from the informer cache is waited for, as the informer
+// may lag behind a build that was just created.
+func (w *Waiter) observeInformer(ctx context.Context, namespace, name string, observe func(*buildv1.Build) (bool, error)) error {
+	changed := make(chan struct{}, 1)
+	notify := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		build, ok := obj.(*buildv1.Build)
+		if !ok || build.Namespace != namespace || build.Name != name {
+			return
+		}
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	registration, err := w.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, obj interface{}) { notify(obj) },
+		DeleteFunc: notify,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = w.informer.RemoveEventHandler(registration) }()
+
+	if !cache.WaitForCacheSync(ctx.Done(), registration.HasSynced) {
+		return ctx.Err()
+	}
+	seen := false
+	for {
+		build, err := w.lister.Builds(namespace).Get(name)
+		switch {
+		case errors.IsNotFound(err) && seen:
+			return fmt.Errorf("build %s/%s was deleted before it finished", namespace, name)
+		case errors.IsNotFound(err):
+		case err != nil:
+			return err
+		default:
+			seen = true
+			if done, err := observe(build); done || err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package waiter
+
+import (
+	context "context"
+	errors "errors"
+	strings "strings"
+	sync "sync"
+	testing "testing"
+	time "time"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	fake "github.com/openshift/client-go/build/clientset/versioned/fake"
+	externalversions "github.com/openshift/client-go/build/informers/externalversions"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	wait "k8s.io/apimachinery/pkg/util/wait"
+	watch "k8s.io/apimachinery/pkg/watch"
+	clientfeatures "k8s.io/client-go/features"
+	clientfeaturestesting "k8s.io/client-go/features/testing"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// harness runs a Waiter against a fake clientset and lets the test move the build between
+// phases once the waiter watches it.
+type harness struct {
+	client   *fake.Clientset
+	watching chan struct{}
+}
+
+func newHarness(objects ...runtime.Object) *harness {
+	h := &harness{client: fake.NewSimpleClientset(objects...), watching: make(chan struct{})}
+	var once sync.Once
+	h.client.PrependWatchReactor("builds", func(clienttesting.Action) (bool, watch.Interface, error) {
+		once.Do(func() { close(h.watching) })
+		return false, nil, nil
+	})
+	return h
+}
+
+// waiter returns a Waiter of the given kind. An informer waiter has its informer started and
+// stopped with ctx.
+func (h *harness) waiter(ctx context.Context, informer bool) *Waiter {
+	if !informer {
+		return NewWaiter(h.client.BuildV1())
+	}
+	factory := externalversions.NewSharedInformerFactory(h.client, 0)
+	w := NewInformerWaiter(factory.Build().V1().Builds())
+	factory.Start(ctx.Done())
+	return w
+}
+
+func (h *harness) setPhase(t *testing.T, phase buildv1.BuildPhase) {
+	t.Helper()
+	select {
+	case <-h.watching:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("build was never watched")
+	}
+	build, err := h.client.BuildV1().Builds("ns").Get(context.TODO(), "app-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.Status.Phase = phase
+	if _, err := h.client.BuildV1().Builds("ns").Update(context.TODO(), build, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWait(t *testing.T) {
+	// The reflector of UntilWithSync cannot stream the initial list from typed fake clients.
+	clientfeaturestesting.SetFeatureDuringTest(t, clientfeatures.WatchListClient, false)
+
+	tests := []struct {
+		name      string
+		phases    []buildv1.BuildPhase
+		wantPhase buildv1.BuildPhase
+	}{
+		{
+			name:   "already complete",
+			phases: []buildv1.BuildPhase{buildv1.BuildPhaseComplete},
+		},
+		{
+			name:   "runs to completion",
+			phases: []buildv1.BuildPhase{buildv1.BuildPhaseNew, buildv1.BuildPhasePending, buildv1.BuildPhaseRunning, buildv1.BuildPhaseComplete},
+		},
+		{
+			name:      "fails",
+			phases:    []buildv1.BuildPhase{buildv1.BuildPhaseRunning, buildv1.BuildPhaseFailed},
+			wantPhase: buildv1.BuildPhaseFailed,
+		},
+		{
+			name:      "cancelled",
+			phases:    []buildv1.BuildPhase{buildv1.BuildPhasePending, buildv1.BuildPhaseCancelled},
+			wantPhase: buildv1.BuildPhaseCancelled,
+		},
+	}
+	for _, test := range tests {
+		for _, informer := range []bool{false, true} {
+			name := test.name
+			if informer {
+				name += " through informer"
+			}
+			t.Run(name, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+				defer cancel()
+				h := newHarness(&buildv1.Build{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app-1"},
+					Status:     buildv1.BuildStatus{Phase: test.phases[0]},
+				})
+				w := h.waiter(ctx, informer)
+
+				events := make(chan Event)
+				type result struct {
+					build *buildv1.Build
+					err   error
+				}
+				done := make(chan result, 1)
+				go func() {
+					build, err := w.Wait(ctx, "ns", "app-1", events)
+					done <- result{build, err}
+				}()
+
+				for i, phase := range test.phases {
+					event := <-events
+					if event.Phase != phase {
+						t.Fatalf("event %d: expected phase %s, got %s", i, phase, event.Phase)
+					}
+					if i > 0 && event.PreviousPhase != test.phases[i-1] {
+						t.Errorf("event %d: expected previous phase %s, got %s", i, test.phases[i-1], event.PreviousPhase)
+					}
+					if i+1 < len(test.phases) {
+						h.setPhase(t, test.phases[i+1])
+					}
+				}
+
+				r := <-done
+				if r.build == nil || r.build.Status.Phase != test.phases[len(test.phases)-1] {
+					t.Errorf("unexpected final build %#v", r.build)
+				}
+				var buildErr *BuildError
+				switch {
+				case len(test.wantPhase) == 0 && r.err != nil:
+					t.Errorf("unexpected error: %v", r.err)
+				case len(test.wantPhase) > 0 && !errors.As(r.err, &buildErr):
+					t.Errorf("expected a *BuildError, got %v", r.err)
+				case len(test.wantPhase) > 0 && buildErr.Phase != test.wantPhase:
+					t.Errorf("expected phase %s in error, got %s", test.wantPhase, buildErr.Phase)
+				}
+			})
+		}
+	}
+}
+
+func TestWaitDeleted(t *testing.T) {
+	clientfeaturestesting.SetFeatureDuringTest(t, clientfeatures.WatchListClient, false)
+
+	for _, informer := range []bool{false, true} {
+		ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+		h := newHarness(&buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app-1"},
+			Status:     buildv1.BuildStatus{Phase: buildv1.BuildPhaseRunning},
+		})
+		w := h.waiter(ctx, informer)
+
+		events := make(chan Event, 1)
+		done := make(chan error, 1)
+		go func() {
+			_, err := w.Wait(ctx, "ns", "app-1", events)
+			done <- err
+		}()
+		<-events
+		<-h.watching
+		if err := h.client.BuildV1().Builds("ns").Delete(ctx, "app-1", metav1.DeleteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err == nil || !strings.Contains(err.Error(), "was deleted") {
+			t.Errorf("informer %t: expected a deletion error, got %v", informer, err)
+		}
+		cancel()
+	}
+}
+
+func TestWaitMissing(t *testing.T) {
+	clientfeaturestesting.SetFeatureDuringTest(t, clientfeatures.WatchListClient, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+	defer cancel()
+	_, err := NewWaiter(fake.NewSimpleClientset().BuildV1()).Wait(ctx, "ns", "app-1", nil)
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	hmac "crypto/hmac"
+	sha256 "crypto/sha256"
+	hex "encoding/hex"
+	json "encoding/json"
+	fmt "fmt"
+	http "net/http"
+	strings "strings"
+	time "time"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Revision describes the commit a webhook payload announces.
+type Revision struct {
+	// Ref is the pushed branch or tag. When empty, the ref of the build config git source is
+	// used so that the server does not discard the event as targeting another branch.
+	Ref string
+	// Commit is the hash of the pushed commit.
+	Commit    string
+	Message   string
+	Author    buildv1.SourceControlUser
+	Committer buildv1.SourceControlUser
+	// Env is passed to the builder container. Only honored by generic webhooks whose trigger
+	// sets AllowEnv.
+	Env []corev1.EnvVar
+}
+
+// Payload is the body and headers of a webhook invocation.
+type Payload struct {
+	Body   []byte
+	Header http.Header
+}
+
+// NewPayload returns the payload the source hosting service for triggerType would send for
+// revision, signed with secret where the service signs its deliveries.
+func NewPayload(buildConfig *buildv1.BuildConfig, triggerType buildv1.BuildTriggerType, revision *Revision, secret string) (*Payload, error) {
+	if revision == nil {
+		revision = &Revision{}
+	}
+	uri, ref := "", revision.Ref
+	if git := buildConfig.Spec.Source.Git; git != nil {
+		uri = git.URI
+		if len(ref) == 0 {
+			ref = git.Ref
+		}
+	}
+	if len(ref) == 0 {
+		ref = "master"
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	var event interface{}
+	switch NormalizeTriggerType(triggerType) {
+	case buildv1.GenericWebHookBuildTriggerType:
+		event = &buildv1.GenericWebHookEvent{
+			Type: buildv1.BuildSourceGit,
+			Git: &buildv1.GitInfo{
+				GitBuildSource: buildv1.GitBuildSource{URI: uri, Ref: ref},
+				GitSourceRevision: buildv1.GitSourceRevision{
+					Commit:    revision.Commit,
+					Author:    revision.Author,
+					Committer: revision.Committer,
+					Message:   revision.Message,
+				},
+			},
+			Env: revision.Env,
+		}
+	case buildv1.GitHubWebHookBuildTriggerType:
+		header.Set("User-Agent", "GitHub-Hookshot/openshift-client-go")
+		header.Set("X-GitHub-Event", "push")
+		event = &gitHubPushEvent{
+			Ref:   qualifiedRef(ref),
+			After: revision.Commit,
+			HeadCommit: gitHubCommit{
+				ID:        revision.Commit,
+				Message:   revision.Message,
+				Author:    revision.Author,
+				Committer: revision.Committer,
+			},
+		}
+	case buildv1.GitLabWebHookBuildTriggerType:
+		header.Set("X-Gitlab-Event", "Push Hook")
+		header.Set("X-Gitlab-Token", secret)
+		event = &gitLabPushEvent{
+			ObjectKind: "push",
+			Ref:        qualifiedRef(ref),
+			After:      revision.Commit,
+			Commits: []gitLabCommit{{
+				ID:      revision.Commit,
+				Message: revision.Message,
+				Author:  revision.Author,
+			}},
+		}
+	case buildv1.BitbucketWebHookBuildTriggerType:
+		header.Set("User-Agent", "Bitbucket-Webhooks/2.0")
+		header.Set("X-Event-Key", "repo:push")
+		refType, refName := "branch", strings.TrimPrefix(ref, "refs/heads/")
+		if strings.HasPrefix(ref, "refs/tags/") {
+			refType, refName = "tag", strings.TrimPrefix(ref, "refs/tags/")
+		}
+		change := bitbucketChange{}
+		change.New.Type = refType
+		change.New.Name = refName
+		change.New.Target = bitbucketCommit{
+			Hash:    revision.Commit,
+			Message: revision.Message,
+			Date:    time.Now().UTC().Format(time.RFC3339),
+			Author:  bitbucketAuthor{Raw: formatUser(revision.Author)},
+		}
+		push := &bitbucketPushEvent{}
+		push.Push.Changes = []bitbucketChange{change}
+		event = push
+	default:
+		return nil, fmt.Errorf("%s is not a webhook trigger type", triggerType)
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	if NormalizeTriggerType(triggerType) == buildv1.GitHubWebHookBuildTriggerType {
+		header.Set("X-Hub-Signature-256", "sha256="+sign(body, secret))
+	}
+	return &Payload{Body: body, Header: header}, nil
+}
+
+// sign returns the hex encoded HMAC-SHA256 of body keyed with secret.
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// qualifiedRef expands a bare branch name into a full git ref.
+func qualifiedRef(ref string) string {
+	if strings.HasPrefix(ref, "refs/") {
+		return ref
+	}
+	return "refs/heads/" + ref
+}
+
+// formatUser renders user the way git formats an author line.
+func formatUser(user buildv1.SourceControlUser) string {
+	if len(user.Email) == 0 {
+		return user.Name
+	}
+	return fmt.Sprintf("%s <%s>", user.Name, user.Email)
+}
+
+type gitHubPushEvent struct {
+	Ref        string       `json:"ref"`
+	After      string       `json:"after"`
+	HeadCommit gitHubCommit `json:"head_commit"`
+}
+
+type gitHubCommit struct {
+	ID        string                    `json:"id"`
+	Message   string                    `json:"message"`
+	Author    buildv1.SourceControlUser `json:"author"`
+	Committer buildv1.SourceControlUser `json:"committer"`
+}
+
+type gitLabPushEvent struct {
+	ObjectKind string         `json:"object_kind"`
+	Ref        string         `json:"ref"`
+	After      string         `json:"after"`
+	Commits    []gitLabCommit `json:"commits"`
+}
+
+type gitLabCommit struct {
+	ID      string                    `json:"id"`
+	Message string                    `json:"message"`
+	Author  buildv1.SourceControlUser `json:"author"`
+}
+
+type bitbucketPushEvent struct {
+	Push struct {
+		Changes []bitbucketChange `json:"changes"`
+	} `json:"push"`
+}
+
+type bitbucketChange struct {
+	New struct {
+		Type   string          `json:"type"`
+		Name   string          `json:"name"`
+		Target bitbucketCommit `json:"target"`
+	} `json:"new"`
+}
+
+type bitbucketCommit struct {
+	Hash    string          `json:"hash"`
+	Message string          `json:"message"`
+	Date    string          `json:"date"`
+	Author  bitbucketAuthor `json:"author"`
+}
+
+type bitbucketAuthor struct {
+	Raw string `json:"raw"`
+}
@@ -0,0 +1,171 @@
+// Package webhook invokes the webhook triggers of build configs through the API server.
+package webhook
+
+import (
+	bytes "bytes"
+	context "context"
+	json "encoding/json"
+	fmt "fmt"
+	strings "strings"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rest "k8s.io/client-go/rest"
+)
+
+// SecretGetter returns the data of the named secret in the given namespace. It is used to
+// resolve webhook triggers that reference their secret instead of embedding it.
+type SecretGetter func(ctx context.Context, namespace, name string) (map[string][]byte, error)
+
+// Trigger fires webhook triggers of build configs.
+type Trigger struct {
+	client  rest.Interface
+	secrets SecretGetter
+}
+
+// NewTrigger returns a Trigger that posts webhook payloads through client, the REST client of the
+// build API group as returned by BuildV1().RESTClient() of a clientset. Fake clientsets have no REST
+// client; tests pass a k8s.io/client-go/rest/fake RESTClient instead. secrets may be nil when none of
+// the triggered webhooks use a secret reference.
+func NewTrigger(client rest.Interface, secrets SecretGetter) (*Trigger, error) {
+	if client == nil {
+		return nil, fmt.Errorf("a REST client is required to fire webhook triggers")
+	}
+	if restClient, ok := client.(*rest.RESTClient); ok && restClient == nil {
+		return nil, fmt.Errorf("a REST client is required to fire webhook triggers, fake clientsets do not provide one")
+	}
+	return &Trigger{client: client, secrets: secrets}, nil
+}
+
+// Fire invokes the webhook trigger of the given type on buildConfig with a payload describing
+// revision, and returns the name of the triggered build. The name is empty when the server
+// accepted the payload without creating a build, for example when the pushed ref does not match
+// the ref of the build config source.
+func (t *Trigger) Fire(ctx context.Context, buildConfig *buildv1.BuildConfig, triggerType buildv1.BuildTriggerType, revision *Revision) (string, error) {
+	trigger, err := FindWebHookTrigger(buildConfig, triggerType)
+	if err != nil {
+		return "", err
+	}
+	secret, err := t.secretFor(ctx, buildConfig.Namespace, trigger)
+	if err != nil {
+		return "", err
+	}
+	if msgs := rest.IsValidPathSegmentName(secret); len(msgs) > 0 {
+		return "", fmt.Errorf("webhook secret of build config %s/%s cannot be used in a URL path: %s", buildConfig.Namespace, buildConfig.Name, strings.Join(msgs, ", "))
+	}
+	payload, err := NewPayload(buildConfig, triggerType, revision, secret)
+	if err != nil {
+		return "", err
+	}
+
+	req := t.client.Post().
+		Namespace(buildConfig.Namespace).
+		Resource("buildconfigs").
+		Name(buildConfig.Name).
+		SubResource("webhooks", secret, pathFor(triggerType)).
+		Body(payload.Body)
+	for key, values := range payload.Header {
+		for _, value := range values {
+			req = req.SetHeader(key, value)
+		}
+	}
+	body, err := req.Do(ctx).Raw()
+	if err != nil {
+		return "", err
+	}
+	return buildNameFrom(body)
+}
+
+// buildNameFrom returns the name of the build described by the response body of a webhook
+// invocation, or an empty name when the response does not describe a build.
+func buildNameFrom(body []byte) (string, error) {
+	body = bytes.TrimSpace(body)
+	// Older servers answer with nothing or a plain status message instead of the triggered build.
+	if len(body) == 0 || body[0] != '{' {
+		return "", nil
+	}
+	typeMeta := &metav1.TypeMeta{}
+	if err := json.Unmarshal(body, typeMeta); err != nil {
+		return "", fmt.Errorf("unable to decode webhook response: %w", err)
+	}
+	switch typeMeta.Kind {
+	case "Build":
+		build := &buildv1.Build{}
+		if err := json.Unmarshal(body, build); err != nil {
+			return "", fmt.Errorf("unable to decode build from webhook response: %w", err)
+		}
+		return build.Name, nil
+	case "Status":
+		status := &metav1.Status{}
+		if err := json.Unmarshal(body, status); err != nil {
+			return "", fmt.Errorf("unable to decode status from webhook response: %w", err)
+		}
+		if status.Status == metav1.StatusFailure {
+			return "", &errors.StatusError{ErrStatus: *status}
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("unexpected %q in webhook response", typeMeta.Kind)
+	}
+}
+
+// FindWebHookTrigger returns the first webhook trigger of the given type defined on buildConfig.
+func FindWebHookTrigger(buildConfig *buildv1.BuildConfig, triggerType buildv1.BuildTriggerType) (*buildv1.WebHookTrigger, error) {
+	for _, policy := range buildConfig.Spec.Triggers {
+		var trigger *buildv1.WebHookTrigger
+		switch NormalizeTriggerType(policy.Type) {
+		case buildv1.GenericWebHookBuildTriggerType:
+			trigger = policy.GenericWebHook
+		case buildv1.GitHubWebHookBuildTriggerType:
+			trigger = policy.GitHubWebHook
+		case buildv1.GitLabWebHookBuildTriggerType:
+			trigger = policy.GitLabWebHook
+		case buildv1.BitbucketWebHookBuildTriggerType:
+			trigger = policy.BitbucketWebHook
+		}
+		if trigger != nil && NormalizeTriggerType(policy.Type) == NormalizeTriggerType(triggerType) {
+			return trigger, nil
+		}
+	}
+	return nil, fmt.Errorf("build config %s/%s has no %s webhook trigger", buildConfig.Namespace, buildConfig.Name, triggerType)
+}
+
+// secretFor returns the secret the server validates invocations of trigger against.
+func (t *Trigger) secretFor(ctx context.Context, namespace string, trigger *buildv1.WebHookTrigger) (string, error) {
+	if trigger.SecretReference == nil {
+		if len(trigger.Secret) == 0 {
+			return "", fmt.Errorf("webhook trigger has no secret")
+		}
+		return trigger.Secret, nil
+	}
+	if t.secrets == nil {
+		return "", fmt.Errorf("webhook trigger references secret %s/%s but no secret getter was provided", namespace, trigger.SecretReference.Name)
+	}
+	data, err := t.secrets(ctx, namespace, trigger.SecretReference.Name)
+	if err != nil {
+		return "", err
+	}
+	secret, ok := data[buildv1.WebHookSecretKey]
+	if !ok || len(secret) == 0 {
+		return "", fmt.Errorf("secret %s/%s has no %q key", namespace, trigger.SecretReference.Name, buildv1.WebHookSecretKey)
+	}
+	return string(secret), nil
+}
+
+// NormalizeTriggerType maps the deprecated generic and github trigger type spellings, which the
+// server still accepts, to their current form.
+func NormalizeTriggerType(triggerType buildv1.BuildTriggerType) buildv1.BuildTriggerType {
+	switch triggerType {
+	case buildv1.GenericWebHookBuildTriggerTypeDeprecated:
+		return buildv1.GenericWebHookBuildTriggerType
+	case buildv1.GitHubWebHookBuildTriggerTypeDeprecated:
+		return buildv1.GitHubWebHookBuildTriggerType
+	}
+	return triggerType
+}
+
+// pathFor returns the webhook URL segment the server routes the given trigger type to.
+func pathFor(triggerType buildv1.BuildTriggerType) string {
+	return strings.ToLower(string(NormalizeTriggerType(triggerType)))
+}
@@ -0,0 +1,222 @@
+package webhook
+
+import (
+	context "context"
+	hmac "crypto/hmac"
+	sha256 "crypto/sha256"
+	hex "encoding/hex"
+	json "encoding/json"
+	io "io"
+	http "net/http"
+	strings "strings"
+	testing "testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	scheme "github.com/openshift/client-go/build/clientset/versioned/scheme"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rest "k8s.io/client-go/rest"
+	fakerest "k8s.io/client-go/rest/fake"
+)
+
+func TestBuildNameFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		want     string
+		wantErr  bool
+		wantFail bool
+	}{
+		{name: "empty body"},
+		{name: "plain text status of older servers", body: "ok\n"},
+		{name: "build", body: `{"kind":"Build","apiVersion":"build.openshift.io/v1","metadata":{"name":"app-3"}}`, want: "app-3"},
+		{name: "successful status", body: `{"kind":"Status","apiVersion":"v1","status":"Success"}`},
+		{name: "failed status", body: `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403}`, wantErr: true, wantFail: true},
+		{name: "unknown kind", body: `{"kind":"Pod"}`, wantErr: true},
+		{name: "malformed json", body: `{"kind":`, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := buildNameFrom([]byte(test.body))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.wantFail && !apierrors.IsForbidden(err) {
+				t.Errorf("expected the failure status as an API error, got %v", err)
+			}
+			if got != test.want {
+				t.Errorf("expected build %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestSecretFor(t *testing.T) {
+	secrets := func(_ context.Context, namespace, name string) (map[string][]byte, error) {
+		switch name {
+		case "webhook":
+			return map[string][]byte{buildv1.WebHookSecretKey: []byte("referenced")}, nil
+		case "wrong-key":
+			return map[string][]byte{"token": []byte("referenced")}, nil
+		}
+		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+	}
+	tests := []struct {
+		name    string
+		trigger buildv1.WebHookTrigger
+		getter  SecretGetter
+		want    string
+		wantErr string
+	}{
+		{name: "embedded", trigger: buildv1.WebHookTrigger{Secret: "embedded"}, want: "embedded"},
+		{name: "none", wantErr: "has no secret"},
+		{name: "referenced", trigger: buildv1.WebHookTrigger{SecretReference: &buildv1.SecretLocalReference{Name: "webhook"}}, getter: secrets, want: "referenced"},
+		{name: "reference without getter", trigger: buildv1.WebHookTrigger{SecretReference: &buildv1.SecretLocalReference{Name: "webhook"}}, wantErr: "no secret getter"},
+		{name: "reference without key", trigger: buildv1.WebHookTrigger{SecretReference: &buildv1.SecretLocalReference{Name: "wrong-key"}}, getter: secrets, wantErr: `no "WebHookSecretKey" key`},
+		{name: "missing secret", trigger: buildv1.WebHookTrigger{SecretReference: &buildv1.SecretLocalReference{Name: "gone"}}, getter: secrets, wantErr: "not found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := (&Trigger{secrets: test.getter}).secretFor(context.TODO(), "ns", &test.trigger)
+			switch {
+			case len(test.wantErr) > 0 && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Fatalf("expected error containing %q, got %v", test.wantErr, err)
+			case len(test.wantErr) == 0 && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("expected secret %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestNewPayload(t *testing.T) {
+	buildConfig := &buildv1.BuildConfig{Spec: buildv1.BuildConfigSpec{CommonSpec: buildv1.CommonSpec{
+		Source: buildv1.BuildSource{Git: &buildv1.GitBuildSource{URI: "https://git.example.com/app.git", Ref: "main"}},
+	}}}
+	revision := &Revision{Commit: "0123abcd", Message: "fix", Author: buildv1.SourceControlUser{Name: "Dev", Email: "dev@example.com"}}
+
+	t.Run("generic", func(t *testing.T) {
+		payload, err := NewPayload(buildConfig, buildv1.GenericWebHookBuildTriggerTypeDeprecated, revision, "s3cret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		event := &buildv1.GenericWebHookEvent{}
+		if err := json.Unmarshal(payload.Body, event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Git == nil || event.Git.Ref != "main" || event.Git.URI != "https://git.example.com/app.git" || event.Git.Commit != "0123abcd" {
+			t.Errorf("unexpected generic event %#v", event.Git)
+		}
+	})
+
+	t.Run("github is signed", func(t *testing.T) {
+		payload, err := NewPayload(buildConfig, buildv1.GitHubWebHookBuildTriggerType, &Revision{Ref: "refs/tags/v1", Commit: "0123abcd"}, "s3cret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(payload.Body)
+		if got, want := payload.Header.Get("X-Hub-Signature-256"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+			t.Errorf("expected signature %s, got %s", want, got)
+		}
+		event := &gitHubPushEvent{}
+		if err := json.Unmarshal(payload.Body, event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Ref != "refs/tags/v1" || event.After != "0123abcd" {
+			t.Errorf("unexpected push event %#v", event)
+		}
+	})
+
+	t.Run("gitlab carries the token", func(t *testing.T) {
+		payload, err := NewPayload(buildConfig, buildv1.GitLabWebHookBuildTriggerType, revision, "s3cret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload.Header.Get("X-Gitlab-Token") != "s3cret" || !strings.Contains(string(payload.Body), `"ref":"refs/heads/main"`) {
+			t.Errorf("unexpected payload %s %v", payload.Body, payload.Header)
+		}
+	})
+
+	t.Run("bitbucket tag", func(t *testing.T) {
+		payload, err := NewPayload(buildConfig, buildv1.BitbucketWebHookBuildTriggerType, &Revision{Ref: "refs/tags/v1"}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(payload.Body), `"type":"tag","name":"v1"`) {
+			t.Errorf("unexpected payload %s", payload.Body)
+		}
+	})
+
+	t.Run("not a webhook", func(t *testing.T) {
+		if _, err := NewPayload(buildConfig, buildv1.ConfigChangeBuildTriggerType, revision, ""); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestFire(t *testing.T) {
+	buildConfig := &buildv1.BuildConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+		Spec: buildv1.BuildConfigSpec{Triggers: []buildv1.BuildTriggerPolicy{
+			{Type: buildv1.GitHubWebHookBuildTriggerTypeDeprecated, GitHubWebHook: &buildv1.WebHookTrigger{Secret: "s3cret"}},
+			{Type: buildv1.GenericWebHookBuildTriggerType, GenericWebHook: &buildv1.WebHookTrigger{Secret: "a/b"}},
+		}},
+	}
+
+	var path string
+	client := &fakerest.RESTClient{
+		Client: fakerest.CreateHTTPClient(func(request *http.Request) (*http.Response, error) {
+			path = request.URL.Path
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"kind":"Build","metadata":{"name":"app-4"}}`)),
+			}, nil
+		}),
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         buildv1.SchemeGroupVersion,
+		VersionedAPIPath:     "/apis/build.openshift.io/v1",
+	}
+	trigger, err := NewTrigger(client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := trigger.Fire(context.TODO(), buildConfig, buildv1.GitHubWebHookBuildTriggerType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "app-4" {
+		t.Errorf("expected build app-4, got %q", name)
+	}
+	if want := "/apis/build.openshift.io/v1/namespaces/ns/buildconfigs/app/webhooks/s3cret/github"; path != want {
+		t.Errorf("expected path %s, got %s", want, path)
+	}
+
+	path = ""
+	if _, err := trigger.Fire(context.TODO(), buildConfig, buildv1.GenericWebHookBuildTriggerType, nil); err == nil || !strings.Contains(err.Error(), "URL path") {
+		t.Errorf("expected the secret to be rejected as a path segment, got %v", err)
+	}
+	if len(path) > 0 {
+		t.Errorf("expected no request for an unusable secret, got %s", path)
+	}
+	if _, err := trigger.Fire(context.TODO(), buildConfig, buildv1.GitLabWebHookBuildTriggerType, nil); err == nil {
+		t.Error("expected an error for a missing trigger")
+	}
+}
+
+func TestNewTriggerWithoutClient(t *testing.T) {
+	var restClient *rest.RESTClient
+	for _, client := range []rest.Interface{nil, restClient} {
+		if _, err := NewTrigger(client, nil); err == nil {
+			t.Errorf("expected an error for %#v", client)
+		}
+	}
+	if _, err := NewTrigger(&fakerest.RESTClient{}, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,224 @@
+// Package credentials resolves registry credentials for pull specs from pull secrets, with the
+// host matching and precedence rules the image import controller uses.
+package credentials
+
+import (
+	context "context"
+	base64 "encoding/base64"
+	json "encoding/json"
+	fmt "fmt"
+	path "path"
+	sort "sort"
+	strings "strings"
+
+	typedimagev1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	reference "github.com/openshift/client-go/image/reference"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AuthConfig holds the credentials for a registry.
+type AuthConfig struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Auth          string `json:"auth,omitempty"`
+	Email         string `json:"email,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+	RegistryToken string `json:"registrytoken,omitempty"`
+}
+
+// dockerConfig is the content of a .dockercfg key, and of the auths of a .dockerconfigjson key.
+type dockerConfig map[string]AuthConfig
+
+type dockerConfigJSON struct {
+	Auths dockerConfig `json:"auths"`
+}
+
+// entry is the credential of one key of a docker config.
+type entry struct {
+	// host is the registry host, possibly with wildcard labels such as *.example.com, and port.
+	host string
+	// path is the repository path prefix the credential is limited to, empty for the whole registry.
+	path string
+	// order is the position of the key among all parsed keys, used to break ties.
+	order int
+	auth  AuthConfig
+}
+
+// Keychain resolves the credentials to use for a pull spec.
+type Keychain struct {
+	entries []entry
+}
+
+// ForImageStream returns the Keychain of the pull secrets the cluster uses to import images into
+// the named image stream.
+func ForImageStream(ctx context.Context, client typedimagev1.ImageStreamsGetter, namespace, name string) (*Keychain, error) {
+	secrets, err := client.ImageStreams(namespace).Secrets(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return NewKeychain(secrets.Items)
+}
+
+// NewKeychain returns the Keychain of the docker config secrets among secrets. Secrets of other
+// types are ignored. When two secrets hold credentials for the same key, the first one wins.
+func NewKeychain(secrets []corev1.Secret) (*Keychain, error) {
+	k := &Keychain{}
+	for _, secret := range secrets {
+		var config dockerConfig
+		switch secret.Type {
+		case corev1.SecretTypeDockercfg:
+			if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &config); err != nil {
+				return nil, fmt.Errorf("unable to parse secret %s/%s: %w", secret.Namespace, secret.Name, err)
+			}
+		case corev1.SecretTypeDockerConfigJson:
+			configJSON := dockerConfigJSON{}
+			if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &configJSON); err != nil {
+				return nil, fmt.Errorf("unable to parse secret %s/%s: %w", secret.Namespace, secret.Name, err)
+			}
+			config = configJSON.Auths
+		default:
+			continue
+		}
+		if err := k.add(config); err != nil {
+			return nil, fmt.Errorf("invalid secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+	}
+	k.sort()
+	return k, nil
+}
+
+// add adds the keys of config in a stable order, decoding the auth field of every credential.
+func (k *Keychain) add(config dockerConfig) error {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		auth := config[key]
+		if len(auth.Auth) > 0 && len(auth.Username) == 0 && len(auth.Password) == 0 {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return fmt.Errorf("invalid auth for %s: %w", key, err)
+			}
+			username, password, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return fmt.Errorf("invalid auth for %s: expected username:password", key)
+			}
+			auth.Username, auth.Password = username, password
+		}
+		host, repository := parseKey(key)
+		if len(host) == 0 {
+			continue
+		}
+		k.entries = append(k.entries, entry{host: host, path: repository, order: len(k.entries), auth: auth})
+	}
+	return nil
+}
+
+// sort orders the entries most specific first: longer repository paths, then hosts without
+// wildcards, then the order the keys were added.
+func (k *Keychain) sort() {
+	sort.SliceStable(k.entries, func(i, j int) bool {
+		a, b := k.entries[i], k.entries[j]
+		if len(a.path) != len(b.path) {
+			return len(a.path) > len(b.path)
+		}
+		if wa, wb := strings.Contains(a.host, "*"), strings.Contains(b.host, "*"); wa != wb {
+			return !wa
+		}
+		return a.order < b.order
+	})
+}
+
+// Lookup returns the credentials matching the pull spec image, most specific first. Short pull
+// specs refer to docker.io.
+func (k *Keychain) Lookup(image string) ([]AuthConfig, error) {
+	ref, err := reference.Parse(image)
+	if err != nil {
+		return nil, err
+	}
+	ref = ref.DockerClientDefaults()
+	host := normalizeHost(ref.Registry)
+	repository := ref.RepositoryName()
+
+	var auths []AuthConfig
+	seen := map[entryKey]bool{}
+	for _, e := range k.entries {
+		if !hostMatches(e.host, host) || !pathMatches(e.path, repository) {
+			continue
+		}
+		// the first secret providing a key wins, later duplicates are shadowed
+		key := entryKey{host: e.host, path: e.path}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		auths = append(auths, e.auth)
+	}
+	return auths, nil
+}
+
+// Resolve returns the most specific credentials for image, false if there are none and the image
+// should be pulled anonymously.
+func (k *Keychain) Resolve(image string) (AuthConfig, bool, error) {
+	auths, err := k.Lookup(image)
+	if err != nil || len(auths) == 0 {
+		return AuthConfig{}, false, err
+	}
+	return auths[0], true, nil
+}
+
+type entryKey struct {
+	host string
+	path string
+}
+
+// parseKey splits a docker config key, such as https://registry.example.com/v1/ or
+// *.example.com/team, into a normalized host and repository path prefix.
+func parseKey(key string) (string, string) {
+	key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	host, repository, _ := strings.Cut(key, "/")
+	// registry API version paths are not part of the repository
+	if repository == "v1" || repository == "v2" || strings.HasPrefix(repository, "v1/") || strings.HasPrefix(repository, "v2/") {
+		repository = repository[2:]
+	}
+	return normalizeHost(host), strings.Trim(repository, "/")
+}
+
+// normalizeHost maps the historical names of docker.io to docker.io.
+func normalizeHost(host string) string {
+	host = strings.ToLower(host)
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return reference.DockerDefaultRegistry
+	}
+	return host
+}
+
+// hostMatches returns true if the host pattern, whose labels may be wildcards, matches host. Both
+// must have the same number of labels and the same port.
+func hostMatches(pattern, host string) bool {
+	patternHost, patternPort, _ := strings.Cut(pattern, ":")
+	hostName, hostPort, _ := strings.Cut(host, ":")
+	if patternPort != hostPort {
+		return false
+	}
+	patternLabels := strings.Split(patternHost, ".")
+	hostLabels := strings.Split(hostName, ".")
+	if len(patternLabels) != len(hostLabels) {
+		return false
+	}
+	for i := range patternLabels {
+		if ok, err := path.Match(patternLabels[i], hostLabels[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// pathMatches returns true if repository is prefix, or below it.
+func pathMatches(prefix, repository string) bool {
+	return len(prefix) == 0 || repository == prefix || strings.HasPrefix(repository, prefix+"/")
+}
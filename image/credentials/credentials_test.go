@@ -0,0 +1,162 @@
+package credentials
+
+import (
+	base64 "encoding/base64"
+	json "encoding/json"
+	reflect "reflect"
+	testing "testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHostMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{pattern: "quay.io", host: "quay.io", want: true},
+		{pattern: "quay.io", host: "docker.io", want: false},
+		{pattern: "*.example.com", host: "registry.example.com", want: true},
+		{pattern: "*.example.com", host: "example.com", want: false},
+		{pattern: "*.example.com", host: "a.registry.example.com", want: false},
+		{pattern: "*.*.example.com", host: "a.registry.example.com", want: true},
+		{pattern: "reg*.example.com", host: "registry.example.com", want: true},
+		{pattern: "registry.example.com:5000", host: "registry.example.com:5000", want: true},
+		{pattern: "registry.example.com:5000", host: "registry.example.com", want: false},
+		{pattern: "registry.example.com", host: "registry.example.com:5000", want: false},
+		{pattern: "[", host: "[", want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+"~"+test.host, func(t *testing.T) {
+			if got := hostMatches(test.pattern, test.host); got != test.want {
+				t.Errorf("expected %t, got %t", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		wantHost string
+		wantPath string
+	}{
+		{key: "https://index.docker.io/v1/", wantHost: "docker.io"},
+		{key: "registry-1.docker.io", wantHost: "docker.io"},
+		{key: "http://Registry.Example.com/v2/team/", wantHost: "registry.example.com", wantPath: "team"},
+		{key: "*.example.com/team/app", wantHost: "*.example.com", wantPath: "team/app"},
+		{key: "quay.io/v1beta/app", wantHost: "quay.io", wantPath: "v1beta/app"},
+		{key: "registry:5000", wantHost: "registry:5000"},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			host, path := parseKey(test.key)
+			if host != test.wantHost || path != test.wantPath {
+				t.Errorf("expected %q %q, got %q %q", test.wantHost, test.wantPath, host, path)
+			}
+		})
+	}
+}
+
+func TestKeychainLookup(t *testing.T) {
+	secrets := []corev1.Secret{
+		dockerConfigJSONSecret("first", map[string]AuthConfig{
+			"quay.io":                     {Username: "quay"},
+			"quay.io/team":                {Username: "quay-team"},
+			"*.example.com":               {Username: "wildcard"},
+			"registry.example.com":        {Username: "registry"},
+			"https://index.docker.io/v1/": {Auth: base64.StdEncoding.EncodeToString([]byte("hub:secret"))},
+		}),
+		dockerConfigJSONSecret("second", map[string]AuthConfig{
+			"quay.io":          {Username: "shadowed"},
+			"quay.io/team/app": {Username: "quay-app"},
+		}),
+		{ObjectMeta: metav1.ObjectMeta{Name: "opaque"}, Type: corev1.SecretTypeOpaque, Data: map[string][]byte{"key": []byte("not json")}},
+	}
+	keychain, err := NewKeychain(secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		image string
+		want  []string
+	}{
+		{image: "quay.io/team/app:v1", want: []string{"quay-app", "quay-team", "quay"}},
+		{image: "quay.io/team/other", want: []string{"quay-team", "quay"}},
+		{image: "quay.io/teams/app", want: []string{"quay"}},
+		{image: "registry.example.com/app", want: []string{"registry", "wildcard"}},
+		{image: "mirror.example.com/app", want: []string{"wildcard"}},
+		{image: "nginx", want: []string{"hub"}},
+		{image: "docker.io/library/nginx", want: []string{"hub"}},
+		{image: "ghcr.io/app", want: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			auths, err := keychain.Lookup(test.image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, auth := range auths {
+				got = append(got, auth.Username)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+
+	auth, ok, err := keychain.Resolve("nginx")
+	if err != nil || !ok || auth.Username != "hub" || auth.Password != "secret" {
+		t.Errorf("expected decoded docker.io credentials, got %#v %t %v", auth, ok, err)
+	}
+	if _, ok, err := keychain.Resolve("ghcr.io/app"); ok || err != nil {
+		t.Errorf("expected no credentials, got %t %v", ok, err)
+	}
+}
+
+func TestNewKeychainErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret corev1.Secret
+	}{
+		{
+			name:   "malformed config",
+			secret: corev1.Secret{Type: corev1.SecretTypeDockerConfigJson, Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte("{")}},
+		},
+		{
+			name:   "malformed dockercfg",
+			secret: corev1.Secret{Type: corev1.SecretTypeDockercfg, Data: map[string][]byte{corev1.DockerConfigKey: []byte("[]")}},
+		},
+		{
+			name:   "auth not base64",
+			secret: dockerConfigJSONSecret("bad", map[string]AuthConfig{"quay.io": {Auth: "%%%"}}),
+		},
+		{
+			name:   "auth without password",
+			secret: dockerConfigJSONSecret("bad", map[string]AuthConfig{"quay.io": {Auth: base64.StdEncoding.EncodeToString([]byte("user"))}}),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewKeychain([]corev1.Secret{test.secret}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func dockerConfigJSONSecret(name string, auths map[string]AuthConfig) corev1.Secret {
+	data, err := json.Marshal(dockerConfigJSON{Auths: auths})
+	if err != nil {
+		panic(err)
+	}
+	return corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: data},
+	}
+}
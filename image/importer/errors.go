@@ -0,0 +1,83 @@
+package importer
+
+import (
+	errors "errors"
+	fmt "fmt"
+	strings "strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Reason classifies why the import of a tag failed.
+type Reason string
+
+const (
+	// ReasonUnauthorized means the registry rejected the credentials, or none were available.
+	ReasonUnauthorized Reason = "Unauthorized"
+	// ReasonNotFound means the repository or tag does not exist in the registry.
+	ReasonNotFound Reason = "NotFound"
+	// ReasonManifestUnknown means the registry has no manifest for the requested tag or digest.
+	ReasonManifestUnknown Reason = "ManifestUnknown"
+	// ReasonFailed covers every other failure, such as an unreachable registry.
+	ReasonFailed Reason = "Failed"
+)
+
+// ImportError describes why the import of a tag failed.
+type ImportError struct {
+	Tag     string
+	From    string
+	Reason  Reason
+	Message string
+}
+
+func (e *ImportError) Error() string {
+	if len(e.Tag) == 0 {
+		return fmt.Sprintf("import of %s failed: %s", e.From, e.Message)
+	}
+	return fmt.Sprintf("import of %s into tag %s failed: %s", e.From, e.Tag, e.Message)
+}
+
+// IsUnauthorized returns true if err is an *ImportError caused by missing or rejected credentials.
+func IsUnauthorized(err error) bool {
+	return reasonFor(err) == ReasonUnauthorized
+}
+
+// IsNotFound returns true if err is an *ImportError caused by a missing repository or tag.
+func IsNotFound(err error) bool {
+	return reasonFor(err) == ReasonNotFound
+}
+
+// IsManifestUnknown returns true if err is an *ImportError caused by a missing manifest.
+func IsManifestUnknown(err error) bool {
+	return reasonFor(err) == ReasonManifestUnknown
+}
+
+func reasonFor(err error) Reason {
+	var importErr *ImportError
+	if errors.As(err, &importErr) {
+		return importErr.Reason
+	}
+	return ""
+}
+
+// errorFor converts the status the server reported for an import into an *ImportError, nil if
+// the import succeeded.
+func errorFor(tag, from string, status metav1.Status) error {
+	if status.Status == metav1.StatusSuccess {
+		return nil
+	}
+	reason := ReasonFailed
+	message := status.Message
+	switch {
+	case status.Reason == metav1.StatusReasonUnauthorized || status.Reason == metav1.StatusReasonForbidden:
+		reason = ReasonUnauthorized
+	case strings.Contains(strings.ToLower(message), "manifest unknown"):
+		reason = ReasonManifestUnknown
+	case status.Reason == metav1.StatusReasonNotFound:
+		reason = ReasonNotFound
+	}
+	if len(message) == 0 {
+		message = string(status.Reason)
+	}
+	return &ImportError{Tag: tag, From: from, Reason: reason, Message: message}
+}
@@ -0,0 +1,190 @@
+// Package importer imports images into image streams and reports the outcome of every tag.
+package importer
+
+import (
+	context "context"
+	fmt "fmt"
+	strings "strings"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	typedimagev1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Source is an image, or a whole repository, to import.
+type Source struct {
+	// From is the pull spec of the image or repository to import.
+	From string
+	// Tag is the image stream tag the image is imported into. It defaults to the tag of From,
+	// or latest. It is ignored for repository imports, which keep the source tag names.
+	Tag string
+	// Repository imports every tag of the repository From instead of a single image.
+	Repository bool
+	// Insecure allows importing from registries without valid TLS certificates.
+	Insecure bool
+	// Scheduled periodically re-imports the source.
+	Scheduled bool
+	// ReferencePolicy controls how the imported image is referenced by consumers.
+	ReferencePolicy imagev1.TagReferencePolicyType
+	// ImportMode controls how manifest lists are imported.
+	ImportMode imagev1.ImportModeType
+}
+
+// Result is the outcome of importing a single tag.
+type Result struct {
+	// Tag is the image stream tag the image was imported into.
+	Tag string
+	// From is the pull spec that was imported.
+	From string
+	// Image is the imported image, nil if the import failed.
+	Image *imagev1.Image
+	// Err is an *ImportError if the import of the tag failed.
+	Err error
+}
+
+// Options configures an import.
+type Options struct {
+	// Wait blocks until the image stream reports the outcome of the import for every tag.
+	Wait bool
+}
+
+// Importer imports images into image streams.
+type Importer struct {
+	client typedimagev1.ImageV1Interface
+}
+
+// NewImporter returns an Importer using client.
+func NewImporter(client typedimagev1.ImageV1Interface) *Importer {
+	return &Importer{client: client}
+}
+
+// Import imports sources into the named image stream, creating the stream if it does not exist,
+// and returns the result for every imported tag. The returned error is only set if an import
+// could not be submitted; failures of individual tags are reported in the results.
+func (i *Importer) Import(ctx context.Context, namespace, stream string, sources []Source, options Options) ([]Result, error) {
+	// An import request accepts any number of images but a single repository.
+	var requests []*imagev1.ImageStreamImport
+	images := newImport(namespace, stream)
+	for _, source := range sources {
+		if source.Repository {
+			request := newImport(namespace, stream)
+			request.Spec.Repository = &imagev1.RepositoryImportSpec{
+				From:            corev1.ObjectReference{Kind: "DockerImage", Name: source.From},
+				ImportPolicy:    importPolicy(source),
+				ReferencePolicy: imagev1.TagReferencePolicy{Type: source.ReferencePolicy},
+			}
+			requests = append(requests, request)
+			continue
+		}
+		images.Spec.Images = append(images.Spec.Images, imagev1.ImageImportSpec{
+			From:            corev1.ObjectReference{Kind: "DockerImage", Name: source.From},
+			To:              &corev1.LocalObjectReference{Name: tagFor(source)},
+			ImportPolicy:    importPolicy(source),
+			ReferencePolicy: imagev1.TagReferencePolicy{Type: source.ReferencePolicy},
+		})
+	}
+	if len(images.Spec.Images) > 0 {
+		requests = append([]*imagev1.ImageStreamImport{images}, requests...)
+	}
+
+	var results []Result
+	var imported *imagev1.ImageStream
+	for _, request := range requests {
+		response, err := i.client.ImageStreamImports(namespace).Create(ctx, request, metav1.CreateOptions{})
+		if err != nil {
+			return results, err
+		}
+		results = append(results, resultsFor(request, response)...)
+		if response.Status.Import != nil {
+			imported = response.Status.Import
+		}
+	}
+
+	if !options.Wait || imported == nil {
+		return results, nil
+	}
+	var tags []string
+	for _, result := range results {
+		if result.Err == nil {
+			tags = append(tags, result.Tag)
+		}
+	}
+	if err := WaitForTags(ctx, i.client, imported, tags); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// resultsFor pairs the status reported for every image of an import with its request.
+func resultsFor(request, response *imagev1.ImageStreamImport) []Result {
+	var results []Result
+	for j, status := range response.Status.Images {
+		result := Result{Tag: status.Tag, Image: status.Image}
+		if j < len(request.Spec.Images) {
+			spec := request.Spec.Images[j]
+			result.From = spec.From.Name
+			if len(result.Tag) == 0 && spec.To != nil {
+				result.Tag = spec.To.Name
+			}
+		}
+		result.Err = errorFor(result.Tag, result.From, status.Status)
+		results = append(results, result)
+	}
+
+	repository := response.Status.Repository
+	if repository == nil {
+		return results
+	}
+	from := request.Spec.Repository.From.Name
+	if err := errorFor("", from, repository.Status); err != nil && len(repository.Images) == 0 {
+		return append(results, Result{From: from, Err: err})
+	}
+	for _, status := range repository.Images {
+		results = append(results, Result{
+			Tag:   status.Tag,
+			From:  from,
+			Image: status.Image,
+			Err:   errorFor(status.Tag, from, status.Status),
+		})
+	}
+	return results
+}
+
+func newImport(namespace, stream string) *imagev1.ImageStreamImport {
+	return &imagev1.ImageStreamImport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: stream},
+		Spec:       imagev1.ImageStreamImportSpec{Import: true},
+	}
+}
+
+func importPolicy(source Source) imagev1.TagImportPolicy {
+	return imagev1.TagImportPolicy{
+		Insecure:   source.Insecure,
+		Scheduled:  source.Scheduled,
+		ImportMode: source.ImportMode,
+	}
+}
+
+// tagFor returns the image stream tag a single image source is imported into.
+func tagFor(source Source) string {
+	if len(source.Tag) > 0 {
+		return source.Tag
+	}
+	name := source.From
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	// a colon after the last slash separates the tag, earlier ones belong to a registry port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		return name[i+1:]
+	}
+	return "latest"
+}
+
+func (r Result) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s: %v", r.Tag, r.Err)
+	}
+	return fmt.Sprintf("%s: imported %s", r.Tag, r.From)
+}
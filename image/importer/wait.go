@@ -0,0 +1,96 @@
+package importer
+
+import (
+	context "context"
+	fmt "fmt"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	typedimagev1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	corev1 "k8s.io/api/core/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fields "k8s.io/apimachinery/pkg/fields"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// WaitForTags watches stream until the import of every given tag at the generation recorded in
+// stream has either produced a tag event or failed with an ImportSuccess condition. It returns
+// an *ImportError for the first failed tag.
+func WaitForTags(ctx context.Context, client typedimagev1.ImageStreamsGetter, stream *imagev1.ImageStream, tags []string) error {
+	pending := map[string]int64{}
+	for _, tag := range tags {
+		pending[tag] = 0
+		for _, ref := range stream.Spec.Tags {
+			if ref.Name == tag && ref.Generation != nil {
+				pending[tag] = *ref.Generation
+			}
+		}
+	}
+	if err := checkTags(stream, pending); err != nil || len(pending) == 0 {
+		return err
+	}
+
+	streams := client.ImageStreams(stream.Namespace)
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", stream.Name).String()
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return streams.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return streams.Watch(ctx, options)
+		},
+	}
+	exists := func(store cache.Store) (bool, error) {
+		if _, ok, err := store.GetByKey(stream.Namespace + "/" + stream.Name); err != nil || !ok {
+			return false, errors.NewNotFound(imagev1.Resource("imagestream"), stream.Name)
+		}
+		return false, nil
+	}
+
+	_, err := watchtools.UntilWithSync(ctx, lw, &imagev1.ImageStream{}, exists, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("image stream %s/%s was deleted before the import finished", stream.Namespace, stream.Name)
+		}
+		updated, ok := event.Object.(*imagev1.ImageStream)
+		if !ok {
+			return false, fmt.Errorf("unexpected object %T in image stream watch", event.Object)
+		}
+		if err := checkTags(updated, pending); err != nil {
+			return false, err
+		}
+		return len(pending) == 0, nil
+	})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// checkTags removes the tags whose import finished from pending, returning an error for the first
+// failed one.
+func checkTags(stream *imagev1.ImageStream, pending map[string]int64) error {
+	for _, history := range stream.Status.Tags {
+		generation, ok := pending[history.Tag]
+		if !ok {
+			continue
+		}
+		for _, condition := range history.Conditions {
+			if condition.Type == imagev1.ImportSuccess && condition.Status == corev1.ConditionFalse && condition.Generation >= generation {
+				return errorFor(history.Tag, "", metav1.Status{
+					Status:  metav1.StatusFailure,
+					Reason:  metav1.StatusReason(condition.Reason),
+					Message: condition.Message,
+				})
+			}
+		}
+		if len(history.Items) > 0 && history.Items[0].Generation >= generation {
+			delete(pending, history.Tag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package importer
+
+import (
+	context "context"
+	strings "strings"
+	sync "sync"
+	testing "testing"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	fake "github.com/openshift/client-go/image/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	wait "k8s.io/apimachinery/pkg/util/wait"
+	watch "k8s.io/apimachinery/pkg/watch"
+	clientfeatures "k8s.io/client-go/features"
+	clientfeaturestesting "k8s.io/client-go/features/testing"
+	clienttesting "k8s.io/client-go/testing"
+	ptr "k8s.io/utils/ptr"
+)
+
+// imported records a tag event for tag at generation.
+func imported(tag string, generation int64) imagev1.NamedTagEventList {
+	return imagev1.NamedTagEventList{Tag: tag, Items: []imagev1.TagEvent{{Image: "sha256:" + tag, Generation: generation}}}
+}
+
+// failed records a failed import of tag at generation.
+func failed(tag string, generation int64, reason string) imagev1.NamedTagEventList {
+	return imagev1.NamedTagEventList{Tag: tag, Conditions: []imagev1.TagEventCondition{{
+		Type:       imagev1.ImportSuccess,
+		Status:     corev1.ConditionFalse,
+		Reason:     reason,
+		Message:    "import failed",
+		Generation: generation,
+	}}}
+}
+
+func TestWaitForTags(t *testing.T) {
+	clientfeaturestesting.SetFeatureDuringTest(t, clientfeatures.WatchListClient, false)
+
+	tests := []struct {
+		name string
+		// status is the tag status of the stream when the wait starts.
+		status []imagev1.NamedTagEventList
+		// updates are applied to the tag status, one update per write, once the wait is watching.
+		updates [][]imagev1.NamedTagEventList
+		// delete removes the stream once the wait is watching.
+		delete bool
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name:   "already imported",
+			status: []imagev1.NamedTagEventList{imported("latest", 2), imported("stable", 2)},
+		},
+		{
+			name:   "imported while waiting",
+			status: []imagev1.NamedTagEventList{imported("latest", 1), failed("stable", 1, "NotFound")},
+			updates: [][]imagev1.NamedTagEventList{
+				{imported("latest", 2), failed("stable", 1, "NotFound")},
+				{imported("latest", 2), imported("stable", 2)},
+			},
+		},
+		{
+			name:    "failed while waiting",
+			status:  []imagev1.NamedTagEventList{imported("latest", 1)},
+			updates: [][]imagev1.NamedTagEventList{{imported("latest", 2), failed("stable", 2, string(metav1.StatusReasonUnauthorized))}},
+			check: func(t *testing.T, err error) {
+				if !IsUnauthorized(err) || !strings.Contains(err.Error(), "tag stable") {
+					t.Errorf("expected an unauthorized import of stable, got %v", err)
+				}
+			},
+		},
+		{
+			name:   "deleted while waiting",
+			delete: true,
+			check: func(t *testing.T, err error) {
+				// the deletion can land before the initial list, in which case the stream is missing
+				if !apierrors.IsNotFound(err) && (err == nil || !strings.Contains(err.Error(), "was deleted")) {
+					t.Errorf("expected the deletion to be reported, got %v", err)
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream := &imagev1.ImageStream{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+				Spec: imagev1.ImageStreamSpec{Tags: []imagev1.TagReference{
+					{Name: "latest", Generation: ptr.To[int64](2)},
+					{Name: "stable", Generation: ptr.To[int64](2)},
+				}},
+				Status: imagev1.ImageStreamStatus{Tags: test.status},
+			}
+			client := fake.NewSimpleClientset(stream)
+			watching := make(chan struct{})
+			var once sync.Once
+			client.PrependWatchReactor("imagestreams", func(clienttesting.Action) (bool, watch.Interface, error) {
+				once.Do(func() { close(watching) })
+				return false, nil, nil
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- WaitForTags(ctx, client.ImageV1(), stream.DeepCopy(), []string{"latest", "stable"}) }()
+
+			if len(test.updates) > 0 || test.delete {
+				select {
+				case <-watching:
+				case err := <-done:
+					t.Fatalf("returned before watching: %v", err)
+				}
+			}
+			streams := client.ImageV1().ImageStreams("ns")
+			for _, status := range test.updates {
+				updated := stream.DeepCopy()
+				updated.Status.Tags = status
+				if _, err := streams.UpdateStatus(ctx, updated, metav1.UpdateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if test.delete {
+				if err := streams.Delete(ctx, "app", metav1.DeleteOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := <-done
+			if test.check != nil {
+				test.check(t, err)
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if ctx.Err() != nil {
+				t.Errorf("wait did not finish before the timeout")
+			}
+		})
+	}
+}
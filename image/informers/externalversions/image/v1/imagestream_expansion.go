@@ -0,0 +1,20 @@
+package v1
+
+import (
+	imagev1 "github.com/openshift/client-go/image/listers/image/v1"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// IndexedImageStreamLister registers ImageStreamDigestIndex on the shared informer of informer,
+// unless already present, and returns a lister that looks ImageStreams up by image digest through it.
+// It must be called before the informer is started, as indexers cannot be added to a running
+// informer; it returns an error otherwise.
+func IndexedImageStreamLister(informer ImageStreamInformer) (imagev1.ImageStreamLister, error) {
+	sharedInformer := informer.Informer()
+	if _, ok := sharedInformer.GetIndexer().GetIndexers()[imagev1.ImageStreamDigestIndex]; !ok {
+		if err := sharedInformer.AddIndexers(cache.Indexers{imagev1.ImageStreamDigestIndex: imagev1.ImageStreamDigestIndexFunc}); err != nil {
+			return nil, err
+		}
+	}
+	return imagev1.NewIndexedImageStreamLister(sharedInformer.GetIndexer()), nil
+}
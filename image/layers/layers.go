@@ -0,0 +1,239 @@
+// Package layers attributes the blobs reported by the layers subresource of an image stream to
+// images, tags and namespaces.
+package layers
+
+import (
+	context "context"
+	sort "sort"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	typedimagev1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Index answers size and ownership questions about the blobs of one image stream. A tag owns
+// every image in its history, not only its current image, because the registry retains all of
+// them.
+type Index struct {
+	namespace string
+	name      string
+	layers    *imagev1.ImageStreamLayers
+	// imageTags maps image names to the tags whose history references them.
+	imageTags map[string][]string
+	// tagImages maps tag names to the images in their history, newest first.
+	tagImages map[string][]string
+	// blobTags maps blob names to the tags that reference them.
+	blobTags map[string][]string
+	tags     []string
+}
+
+// TagUsage is the storage attributed to a tag.
+type TagUsage struct {
+	Tag string
+	// Images is the number of images in the tag history.
+	Images int
+	// Size is the total size of the distinct blobs of the tag.
+	Size int64
+	// SharedBytes is the size of the blobs also referenced by other tags of the stream.
+	SharedBytes int64
+	// UniqueBytes is the size of the blobs referenced by no other tag of the stream, the storage
+	// freed if the tag and its history were deleted.
+	UniqueBytes int64
+}
+
+// Load returns the Index of the named image stream, reading the stream and its layers through
+// client.
+func Load(ctx context.Context, client typedimagev1.ImageStreamsGetter, namespace, name string) (*Index, error) {
+	stream, err := client.ImageStreams(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	layers, err := client.ImageStreams(namespace).Layers(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return NewIndex(stream, layers), nil
+}
+
+// NewIndex returns the Index of stream given the response of its layers subresource.
+func NewIndex(stream *imagev1.ImageStream, layers *imagev1.ImageStreamLayers) *Index {
+	index := &Index{
+		namespace: stream.Namespace,
+		name:      stream.Name,
+		layers:    layers,
+		imageTags: map[string][]string{},
+		tagImages: map[string][]string{},
+		blobTags:  map[string][]string{},
+	}
+	for _, history := range stream.Status.Tags {
+		index.tags = append(index.tags, history.Tag)
+		seen := map[string]bool{}
+		for _, event := range history.Items {
+			if len(event.Image) == 0 || seen[event.Image] {
+				continue
+			}
+			seen[event.Image] = true
+			index.tagImages[history.Tag] = append(index.tagImages[history.Tag], event.Image)
+			index.imageTags[event.Image] = append(index.imageTags[event.Image], history.Tag)
+		}
+		for blob := range index.blobsOf(index.tagImages[history.Tag]...) {
+			index.blobTags[blob] = append(index.blobTags[blob], history.Tag)
+		}
+	}
+	sort.Strings(index.tags)
+	for _, tags := range index.blobTags {
+		sort.Strings(tags)
+	}
+	for _, tags := range index.imageTags {
+		sort.Strings(tags)
+	}
+	return index
+}
+
+// Namespace returns the namespace of the image stream.
+func (i *Index) Namespace() string {
+	return i.namespace
+}
+
+// Name returns the name of the image stream.
+func (i *Index) Name() string {
+	return i.name
+}
+
+// BlobSize returns the size of blob, zero if the size is unknown.
+func (i *Index) BlobSize(blob string) int64 {
+	data, ok := i.layers.Blobs[blob]
+	if !ok || data.LayerSize == nil {
+		return 0
+	}
+	return *data.LayerSize
+}
+
+// Blobs returns the layers and config blobs of image, including those of the images a manifest
+// list points to, sorted by name.
+func (i *Index) Blobs(image string) []string {
+	return sortedKeys(i.blobsOf(image))
+}
+
+// ImageSize returns the total size of the distinct blobs of image, including those of the images
+// a manifest list points to.
+func (i *Index) ImageSize(image string) int64 {
+	return i.sizeOf(i.blobsOf(image))
+}
+
+// ImageMissing returns true if image is referenced by the stream but was deleted from the API, in
+// which case its blobs are unknown.
+func (i *Index) ImageMissing(image string) bool {
+	return i.layers.Images[image].ImageMissing
+}
+
+// Size returns the total size of the distinct blobs of every image of the stream.
+func (i *Index) Size() int64 {
+	blobs := map[string]bool{}
+	for image := range i.layers.Images {
+		for blob := range i.blobsOf(image) {
+			blobs[blob] = true
+		}
+	}
+	return i.sizeOf(blobs)
+}
+
+// TagsForBlob returns the tags whose history references blob, sorted by name.
+func (i *Index) TagsForBlob(blob string) []string {
+	return i.blobTags[blob]
+}
+
+// TagsForImage returns the tags whose history references image, sorted by name.
+func (i *Index) TagsForImage(image string) []string {
+	return i.imageTags[image]
+}
+
+// TagUsage returns the storage attributed to every tag of the stream, sorted by tag name.
+func (i *Index) TagUsage() []TagUsage {
+	var usage []TagUsage
+	for _, tag := range i.tags {
+		blobs := i.blobsOf(i.tagImages[tag]...)
+		u := TagUsage{Tag: tag, Images: len(i.tagImages[tag]), Size: i.sizeOf(blobs)}
+		for blob := range blobs {
+			if len(i.blobTags[blob]) > 1 {
+				u.SharedBytes += i.BlobSize(blob)
+			} else {
+				u.UniqueBytes += i.BlobSize(blob)
+			}
+		}
+		usage = append(usage, u)
+	}
+	return usage
+}
+
+// blobsOf returns the distinct blobs of images, following manifest lists to the images they point
+// to.
+func (i *Index) blobsOf(images ...string) map[string]bool {
+	blobs := map[string]bool{}
+	visited := map[string]bool{}
+	var visit func(image string)
+	visit = func(image string) {
+		if visited[image] {
+			return
+		}
+		visited[image] = true
+		refs, ok := i.layers.Images[image]
+		if !ok {
+			return
+		}
+		for _, layer := range refs.Layers {
+			blobs[layer] = true
+		}
+		if refs.Config != nil {
+			blobs[*refs.Config] = true
+		}
+		for _, manifest := range refs.Manifests {
+			visit(manifest)
+		}
+	}
+	for _, image := range images {
+		visit(image)
+	}
+	return blobs
+}
+
+func (i *Index) sizeOf(blobs map[string]bool) int64 {
+	var size int64
+	for blob := range blobs {
+		size += i.BlobSize(blob)
+	}
+	return size
+}
+
+// NamespaceSizes returns the total size of the distinct blobs referenced by the image streams of
+// every namespace. Blobs shared by several streams of a namespace are counted once, blobs shared
+// across namespaces are counted in each of them.
+func NamespaceSizes(indexes []*Index) map[string]int64 {
+	blobs := map[string]map[string]int64{}
+	for _, index := range indexes {
+		if blobs[index.namespace] == nil {
+			blobs[index.namespace] = map[string]int64{}
+		}
+		for image := range index.layers.Images {
+			for blob := range index.blobsOf(image) {
+				blobs[index.namespace][blob] = index.BlobSize(blob)
+			}
+		}
+	}
+	sizes := map[string]int64{}
+	for namespace, sized := range blobs {
+		for _, size := range sized {
+			sizes[namespace] += size
+		}
+	}
+	return sizes
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
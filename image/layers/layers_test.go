@@ -0,0 +1,134 @@
+package layers
+
+import (
+	context "context"
+	reflect "reflect"
+	testing "testing"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	fake "github.com/openshift/client-go/image/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+	ptr "k8s.io/utils/ptr"
+)
+
+// sizes are the recorded sizes of the blobs. The registry also knows the blob unsized, without a
+// size.
+var sizes = map[string]int64{
+	"base":  100,
+	"app-a": 10,
+	"app-b": 20,
+	"cfg-a": 1,
+	"cfg-b": 2,
+	"c":     50,
+	"cfg-c": 3,
+}
+
+// streamLayers returns the layers subresource of a stream whose images are built from sizes. The
+// manifest list "list" points to img-c and img-a, and "gone" was deleted from the API.
+func streamLayers(namespace, name string) *imagev1.ImageStreamLayers {
+	layers := &imagev1.ImageStreamLayers{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Blobs:      map[string]imagev1.ImageLayerData{"unsized": {}},
+		Images: map[string]imagev1.ImageBlobReferences{
+			"img-a": {Layers: []string{"base", "app-a"}, Config: ptr.To("cfg-a")},
+			"img-b": {Layers: []string{"base", "app-b"}, Config: ptr.To("cfg-b")},
+			"img-c": {Layers: []string{"base", "c", "unsized"}, Config: ptr.To("cfg-c")},
+			"list":  {Manifests: []string{"img-c", "img-a"}},
+			"gone":  {ImageMissing: true},
+		},
+	}
+	for blob, size := range sizes {
+		layers.Blobs[blob] = imagev1.ImageLayerData{LayerSize: ptr.To(size)}
+	}
+	return layers
+}
+
+func stream(namespace, name string, tags map[string][]string) *imagev1.ImageStream {
+	stream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	for tag, images := range tags {
+		history := imagev1.NamedTagEventList{Tag: tag}
+		for _, image := range images {
+			history.Items = append(history.Items, imagev1.TagEvent{Image: image})
+		}
+		stream.Status.Tags = append(stream.Status.Tags, history)
+	}
+	return stream
+}
+
+func TestIndex(t *testing.T) {
+	index := NewIndex(stream("ns", "app", map[string][]string{
+		// img-a is listed twice, and an event without image is ignored
+		"latest": {"img-b", "img-a", "gone", "img-a", ""},
+		"stable": {"img-a"},
+		"multi":  {"list"},
+	}), streamLayers("ns", "app"))
+
+	if got, want := index.TagUsage(), []TagUsage{
+		{Tag: "latest", Images: 3, Size: 133, SharedBytes: 111, UniqueBytes: 22},
+		{Tag: "multi", Images: 1, Size: 164, SharedBytes: 111, UniqueBytes: 53},
+		{Tag: "stable", Images: 1, Size: 111, SharedBytes: 111, UniqueBytes: 0},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected tag usage %+v, got %+v", want, got)
+	}
+
+	if got := index.Blobs("list"); !reflect.DeepEqual(got, []string{"app-a", "base", "c", "cfg-a", "cfg-c", "unsized"}) {
+		t.Errorf("unexpected blobs of the manifest list %v", got)
+	}
+	if got := index.ImageSize("img-b"); got != 122 {
+		t.Errorf("expected img-b to be 122 bytes, got %d", got)
+	}
+	if got := index.Size(); got != 186 {
+		t.Errorf("expected the stream to be 186 bytes, got %d", got)
+	}
+	if !index.ImageMissing("gone") || index.ImageMissing("img-a") || index.ImageMissing("unknown") {
+		t.Errorf("only gone should be missing")
+	}
+	if got := index.TagsForBlob("base"); !reflect.DeepEqual(got, []string{"latest", "multi", "stable"}) {
+		t.Errorf("unexpected tags of base %v", got)
+	}
+	if got := index.TagsForBlob("c"); !reflect.DeepEqual(got, []string{"multi"}) {
+		t.Errorf("unexpected tags of c %v", got)
+	}
+	if got := index.TagsForImage("img-a"); !reflect.DeepEqual(got, []string{"latest", "stable"}) {
+		t.Errorf("unexpected tags of img-a %v", got)
+	}
+	if index.BlobSize("unsized") != 0 || index.BlobSize("unknown") != 0 {
+		t.Errorf("expected unknown sizes to be zero")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	client := fake.NewSimpleClientset(stream("ns", "app", map[string][]string{"latest": {"img-b"}}))
+	client.PrependReactor("get", "imagestreams", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "layers" {
+			return false, nil, nil
+		}
+		return true, streamLayers(action.GetNamespace(), action.(clienttesting.GetAction).GetName()), nil
+	})
+
+	index, err := Load(context.TODO(), client.ImageV1(), "ns", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Namespace() != "ns" || index.Name() != "app" || index.TagUsage()[0].Size != 122 {
+		t.Errorf("unexpected index %s/%s with usage %+v", index.Namespace(), index.Name(), index.TagUsage())
+	}
+	if _, err := Load(context.TODO(), client.ImageV1(), "ns", "missing"); err == nil {
+		t.Errorf("expected an error for a missing stream")
+	}
+}
+
+func TestNamespaceSizes(t *testing.T) {
+	// every stream reports the same blobs, as the layers subresource would for streams tagging
+	// the same images
+	indexes := []*Index{
+		NewIndex(stream("ns1", "a", nil), streamLayers("ns1", "a")),
+		NewIndex(stream("ns1", "b", nil), streamLayers("ns1", "b")),
+		NewIndex(stream("ns2", "c", nil), streamLayers("ns2", "c")),
+	}
+	if got, want := NamespaceSizes(indexes), map[string]int64{"ns1": 186, "ns2": 186}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -6,14 +6,6 @@ package v1
 // ImageLister.
 type ImageListerExpansion interface{}
 
-// ImageStreamListerExpansion allows custom methods to be added to
-// ImageStreamLister.
-type ImageStreamListerExpansion interface{}
-
-// ImageStreamNamespaceListerExpansion allows custom methods to be added to
-// ImageStreamNamespaceLister.
-type ImageStreamNamespaceListerExpansion interface{}
-
 // ImageStreamTagListerExpansion allows custom methods to be added to
 // ImageStreamTagLister.
 type ImageStreamTagListerExpansion interface{}
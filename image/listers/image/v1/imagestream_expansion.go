@@ -0,0 +1,120 @@
+package v1
+
+import (
+	sort "sort"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// ImageStreamDigestIndex is the name of the index of ImageStreams by the digests of the images
+// in their tag history, computed by ImageStreamDigestIndexFunc.
+const ImageStreamDigestIndex = "image.openshift.io/digest"
+
+// ImageStreamDigestIndexFunc indexes an ImageStream by the digest of every image in its tag history.
+// Register it on an informer under ImageStreamDigestIndex and use NewIndexedImageStreamLister to
+// look streams up by digest without listing them all, or let IndexedImageStreamLister of the
+// informers package do both.
+func ImageStreamDigestIndexFunc(obj interface{}) ([]string, error) {
+	stream, ok := obj.(*imagev1.ImageStream)
+	if !ok {
+		return nil, nil
+	}
+	seen := map[string]bool{}
+	var digests []string
+	for _, history := range stream.Status.Tags {
+		for _, event := range history.Items {
+			if len(event.Image) > 0 && !seen[event.Image] {
+				seen[event.Image] = true
+				digests = append(digests, event.Image)
+			}
+		}
+	}
+	return digests, nil
+}
+
+// ImageStreamTagRef identifies a tag of an ImageStream.
+type ImageStreamTagRef struct {
+	Namespace string
+	Stream    string
+	Tag       string
+}
+
+// ImageStreamListerExpansion allows custom methods to be added to
+// ImageStreamLister.
+type ImageStreamListerExpansion interface {
+	// ByImageDigest lists the tags of all ImageStreams whose history references the image digest,
+	// ordered by namespace, stream and tag. The lister returned by NewImageStreamLister scans every
+	// ImageStream on each call and is only suited to one-off lookups. Callers that look digests up
+	// repeatedly must use a lister created by NewIndexedImageStreamLister on an indexer with
+	// ImageStreamDigestIndex registered, as IndexedImageStreamLister of the informers package returns.
+	ByImageDigest(digest string) ([]ImageStreamTagRef, error)
+}
+
+// ImageStreamNamespaceListerExpansion allows custom methods to be added to
+// ImageStreamNamespaceLister.
+type ImageStreamNamespaceListerExpansion interface{}
+
+func (s *imageStreamLister) ByImageDigest(digest string) ([]ImageStreamTagRef, error) {
+	streams, err := s.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	return tagsForDigest(streams, digest), nil
+}
+
+// indexedImageStreamLister looks ImageStreams up by digest through ImageStreamDigestIndex.
+type indexedImageStreamLister struct {
+	ImageStreamLister
+	indexer cache.Indexer
+}
+
+// NewIndexedImageStreamLister returns a new ImageStreamLister whose ByImageDigest uses
+// ImageStreamDigestIndex of indexer. It falls back to listing all ImageStreams if indexer does not
+// have the index.
+func NewIndexedImageStreamLister(indexer cache.Indexer) ImageStreamLister {
+	return &indexedImageStreamLister{ImageStreamLister: NewImageStreamLister(indexer), indexer: indexer}
+}
+
+func (s *indexedImageStreamLister) ByImageDigest(digest string) ([]ImageStreamTagRef, error) {
+	if _, ok := s.indexer.GetIndexers()[ImageStreamDigestIndex]; !ok {
+		return s.ImageStreamLister.ByImageDigest(digest)
+	}
+	objs, err := s.indexer.ByIndex(ImageStreamDigestIndex, digest)
+	if err != nil {
+		return nil, err
+	}
+	streams := make([]*imagev1.ImageStream, 0, len(objs))
+	for _, obj := range objs {
+		if stream, ok := obj.(*imagev1.ImageStream); ok {
+			streams = append(streams, stream)
+		}
+	}
+	return tagsForDigest(streams, digest), nil
+}
+
+// tagsForDigest returns the tags of streams whose history references digest.
+func tagsForDigest(streams []*imagev1.ImageStream, digest string) []ImageStreamTagRef {
+	var refs []ImageStreamTagRef
+	for _, stream := range streams {
+		for _, history := range stream.Status.Tags {
+			for _, event := range history.Items {
+				if event.Image == digest {
+					refs = append(refs, ImageStreamTagRef{Namespace: stream.Namespace, Stream: stream.Name, Tag: history.Tag})
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Namespace != refs[j].Namespace {
+			return refs[i].Namespace < refs[j].Namespace
+		}
+		if refs[i].Stream != refs[j].Stream {
+			return refs[i].Stream < refs[j].Stream
+		}
+		return refs[i].Tag < refs[j].Tag
+	})
+	return refs
+}
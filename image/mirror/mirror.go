@@ -0,0 +1,317 @@
+// Package mirror plans the copy of the images referenced by image streams into a mirror registry
+// for disconnected clusters.
+package mirror
+
+import (
+	fmt "fmt"
+	io "io"
+	sort "sort"
+	strings "strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	applyconfigv1 "github.com/openshift/client-go/config/applyconfigurations/config/v1"
+	listersconfigv1 "github.com/openshift/client-go/config/listers/config/v1"
+	listersimagev1 "github.com/openshift/client-go/image/listers/image/v1"
+	reference "github.com/openshift/client-go/image/reference"
+	labels "k8s.io/apimachinery/pkg/labels"
+)
+
+// Options configures a Planner.
+type Options struct {
+	// Registry is the host, and optional port, of the mirror registry.
+	Registry string
+	// Prefix is prepended to the repository path of every mirrored image, may be empty.
+	Prefix string
+	// Namespace restricts planning to a single namespace, all namespaces when empty.
+	Namespace string
+	// AllHistory mirrors every image in the tag history instead of only the current images.
+	AllHistory bool
+}
+
+// Mapping copies one source image to a mirror.
+type Mapping struct {
+	// Source is the pull spec of the image, by digest unless the tag only references a tag.
+	Source string
+	// Destination is the repository, or pull spec for tag sources, the image is copied to.
+	Destination string
+	// Existing is true if Destination comes from an existing ImageDigestMirrorSet or
+	// ImageTagMirrorSet, in which case the image is not part of the generated mirror set.
+	Existing bool
+	// Tags are the image stream tags referencing the image, as namespace/stream:tag.
+	Tags []string
+}
+
+// Plan is the set of images to mirror.
+type Plan struct {
+	Mappings []Mapping
+}
+
+// Planner computes mirroring plans from image streams and the mirror sets of the cluster.
+type Planner struct {
+	streams    listersimagev1.ImageStreamLister
+	digestSets listersconfigv1.ImageDigestMirrorSetLister
+	tagSets    listersconfigv1.ImageTagMirrorSetLister
+	options    Options
+}
+
+// NewPlanner returns a Planner reading image streams from streams. digestSets and tagSets may be
+// nil, in which case existing mirror configuration is ignored.
+func NewPlanner(streams listersimagev1.ImageStreamLister, digestSets listersconfigv1.ImageDigestMirrorSetLister, tagSets listersconfigv1.ImageTagMirrorSetLister, options Options) *Planner {
+	return &Planner{
+		streams:    streams,
+		digestSets: digestSets,
+		tagSets:    tagSets,
+		options:    options,
+	}
+}
+
+// Plan returns the images referenced by image streams that live outside the integrated registry,
+// with the repository each of them is mirrored to, sorted by source.
+func (p *Planner) Plan() (*Plan, error) {
+	var streams []*imagev1.ImageStream
+	var err error
+	if len(p.options.Namespace) > 0 {
+		streams, err = p.streams.ImageStreams(p.options.Namespace).List(labels.Everything())
+	} else {
+		streams, err = p.streams.List(labels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+	digestMirrors, tagMirrors, err := p.existingMirrors()
+	if err != nil {
+		return nil, err
+	}
+
+	mappings := map[string]*Mapping{}
+	add := func(ref reference.DockerImageReference, tag string, mirrors []mirrorRule) {
+		source := ref.String()
+		mapping, ok := mappings[source]
+		if !ok {
+			mapping = &Mapping{Source: source}
+			if destination, ok := mirrorFor(mirrors, ref); ok {
+				mapping.Destination, mapping.Existing = destination, true
+			} else {
+				mapping.Destination = p.destinationFor(ref)
+			}
+			mappings[source] = mapping
+		}
+		mapping.Tags = append(mapping.Tags, tag)
+	}
+
+	internal := internalRegistries(streams)
+	for _, stream := range streams {
+		for _, history := range stream.Status.Tags {
+			tag := fmt.Sprintf("%s/%s:%s", stream.Namespace, stream.Name, history.Tag)
+			for i, event := range history.Items {
+				if i > 0 && !p.options.AllHistory {
+					break
+				}
+				ref, err := reference.Parse(event.DockerImageReference)
+				if err != nil || internal[ref.Registry] {
+					continue
+				}
+				if len(event.Image) > 0 {
+					ref.ID = event.Image
+				}
+				if len(ref.ID) == 0 {
+					continue
+				}
+				add(ref.MostSpecific().DockerClientDefaults(), tag, digestMirrors)
+			}
+		}
+		// reference tags are never imported, the runtime pulls their pull spec directly
+		for _, spec := range stream.Spec.Tags {
+			if !spec.Reference || spec.From == nil || spec.From.Kind != "DockerImage" || hasHistory(stream, spec.Name) {
+				continue
+			}
+			ref, err := reference.Parse(spec.From.Name)
+			if err != nil || internal[ref.Registry] {
+				continue
+			}
+			mirrors := tagMirrors
+			if len(ref.ID) > 0 {
+				mirrors = digestMirrors
+			}
+			add(ref.MostSpecific().DockerClientDefaults(), fmt.Sprintf("%s/%s:%s", stream.Namespace, stream.Name, spec.Name), mirrors)
+		}
+	}
+
+	plan := &Plan{}
+	for _, mapping := range mappings {
+		sort.Strings(mapping.Tags)
+		plan.Mappings = append(plan.Mappings, *mapping)
+	}
+	sort.Slice(plan.Mappings, func(i, j int) bool {
+		return plan.Mappings[i].Source < plan.Mappings[j].Source
+	})
+	return plan, nil
+}
+
+// destinationFor returns the repository of the mirror registry ref is copied to, keeping the
+// repository path so that a single mirror set entry covers a whole source repository. Tag
+// sources keep their tag.
+func (p *Planner) destinationFor(ref reference.DockerImageReference) string {
+	mirror := p.mirrorRepository(ref)
+	if len(ref.ID) == 0 && len(ref.Tag) > 0 {
+		return mirror + ":" + ref.Tag
+	}
+	return mirror
+}
+
+// mirrorRepository returns the mirror repository of the repository of ref.
+func (p *Planner) mirrorRepository(ref reference.DockerImageReference) string {
+	parts := []string{p.options.Registry}
+	if len(p.options.Prefix) > 0 {
+		parts = append(parts, strings.Trim(p.options.Prefix, "/"))
+	}
+	parts = append(parts, ref.DockerClientDefaults().RepositoryName())
+	return strings.Join(parts, "/")
+}
+
+// WriteMapping writes the plan in the mapping file format of oc image mirror, one
+// source=destination line per image.
+func (p *Plan) WriteMapping(w io.Writer) error {
+	for _, mapping := range p.Mappings {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", mapping.Source, mapping.Destination); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImageDigestMirrorSet returns a mirror set named name redirecting digest pulls of every source
+// repository of the plan to its mirror. Images already covered by an existing mirror set and tag
+// sources are left out. It returns nil if there is nothing to redirect.
+func (p *Plan) ImageDigestMirrorSet(name string) *applyconfigv1.ImageDigestMirrorSetApplyConfiguration {
+	mirrors := map[string]string{}
+	for _, mapping := range p.Mappings {
+		if mapping.Existing {
+			continue
+		}
+		ref, err := reference.Parse(mapping.Source)
+		if err != nil || len(ref.ID) == 0 {
+			continue
+		}
+		source := ref.AsRepository().DockerClientDefaults().String()
+		mirrors[source] = mapping.Destination
+	}
+	if len(mirrors) == 0 {
+		return nil
+	}
+	sources := make([]string, 0, len(mirrors))
+	for source := range mirrors {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	spec := applyconfigv1.ImageDigestMirrorSetSpec()
+	for _, source := range sources {
+		spec.WithImageDigestMirrors(applyconfigv1.ImageDigestMirrors().
+			WithSource(source).
+			WithMirrors(configv1.ImageMirror(mirrors[source])))
+	}
+	return applyconfigv1.ImageDigestMirrorSet(name).WithSpec(spec)
+}
+
+// mirrorRule redirects pulls of a source repository, a registry or a wildcard domain to a mirror.
+type mirrorRule struct {
+	source string
+	mirror string
+}
+
+// existingMirrors returns the first mirror of every entry of the cluster mirror sets.
+func (p *Planner) existingMirrors() (digest, tag []mirrorRule, err error) {
+	if p.digestSets != nil {
+		sets, err := p.digestSets.List(labels.Everything())
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, set := range sets {
+			for _, entry := range set.Spec.ImageDigestMirrors {
+				if len(entry.Mirrors) > 0 {
+					digest = append(digest, mirrorRule{source: entry.Source, mirror: string(entry.Mirrors[0])})
+				}
+			}
+		}
+	}
+	if p.tagSets != nil {
+		sets, err := p.tagSets.List(labels.Everything())
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, set := range sets {
+			for _, entry := range set.Spec.ImageTagMirrors {
+				if len(entry.Mirrors) > 0 {
+					tag = append(tag, mirrorRule{source: entry.Source, mirror: string(entry.Mirrors[0])})
+				}
+			}
+		}
+	}
+	return digest, tag, nil
+}
+
+// mirrorFor returns the destination of ref according to the most specific matching rule, the same
+// way the container runtime picks a mirror.
+func mirrorFor(rules []mirrorRule, ref reference.DockerImageReference) (string, bool) {
+	repository := ref.AsRepository().DockerClientDefaults().String()
+	best := -1
+	for i, rule := range rules {
+		if !matches(rule.source, repository) {
+			continue
+		}
+		if best < 0 || len(rule.source) > len(rules[best].source) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", false
+	}
+	rule := rules[best]
+	if strings.HasPrefix(rule.source, "*.") {
+		// wildcard sources replace the registry and keep the repository path
+		return rule.mirror + "/" + ref.DockerClientDefaults().RepositoryName() + tagSuffix(ref), true
+	}
+	return rule.mirror + strings.TrimPrefix(repository, rule.source) + tagSuffix(ref), true
+}
+
+// matches returns true if the mirror set source covers repository.
+func matches(source, repository string) bool {
+	if strings.HasPrefix(source, "*.") {
+		registry := strings.SplitN(repository, "/", 2)[0]
+		return strings.HasSuffix(registry, source[1:])
+	}
+	return repository == source || strings.HasPrefix(repository, source+"/")
+}
+
+func tagSuffix(ref reference.DockerImageReference) string {
+	if len(ref.ID) == 0 && len(ref.Tag) > 0 {
+		return ":" + ref.Tag
+	}
+	return ""
+}
+
+// internalRegistries returns the hosts of the integrated registry, internal and public, as
+// reported by streams. Every repository on these hosts is internal, including those of other
+// image streams an image was pushed to before being tagged into a stream.
+func internalRegistries(streams []*imagev1.ImageStream) map[string]bool {
+	registries := map[string]bool{}
+	for _, stream := range streams {
+		for _, repository := range []string{stream.Status.DockerImageRepository, stream.Status.PublicDockerImageRepository} {
+			if ref, err := reference.Parse(repository); err == nil && len(ref.Registry) > 0 {
+				registries[ref.Registry] = true
+			}
+		}
+	}
+	return registries
+}
+
+func hasHistory(stream *imagev1.ImageStream, tag string) bool {
+	for _, history := range stream.Status.Tags {
+		if history.Tag == tag && len(history.Items) > 0 {
+			return true
+		}
+	}
+	return false
+}
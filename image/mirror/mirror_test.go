@@ -0,0 +1,58 @@
+package mirror
+
+import (
+	reflect "reflect"
+	strings "strings"
+	testing "testing"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	listersimagev1 "github.com/openshift/client-go/image/listers/image/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+func TestPlanSkipsIntegratedRegistry(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	internal := "image-registry.openshift-image-registry.svc:5000"
+	public := "registry.apps.example.com"
+
+	streams := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, stream := range []*imagev1.ImageStream{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+			Spec: imagev1.ImageStreamSpec{Tags: []imagev1.TagReference{
+				{Name: "ref", Reference: true, From: &corev1.ObjectReference{Kind: "DockerImage", Name: public + "/ns/base:1"}},
+				{Name: "upstream", Reference: true, From: &corev1.ObjectReference{Kind: "DockerImage", Name: "quay.io/org/upstream:1"}},
+			}},
+			Status: imagev1.ImageStreamStatus{
+				DockerImageRepository:       internal + "/ns/app",
+				PublicDockerImageRepository: public + "/ns/app",
+				Tags: []imagev1.NamedTagEventList{
+					// pushed to the stream itself
+					{Tag: "latest", Items: []imagev1.TagEvent{{DockerImageReference: internal + "/ns/app@" + digest, Image: digest}}},
+					// pushed to another stream, then tagged into this one
+					{Tag: "build", Items: []imagev1.TagEvent{{DockerImageReference: internal + "/other/builder@" + digest, Image: digest}}},
+					{Tag: "public", Items: []imagev1.TagEvent{{DockerImageReference: public + "/other/builder@" + digest, Image: digest}}},
+					{Tag: "external", Items: []imagev1.TagEvent{{DockerImageReference: "quay.io/org/app:1", Image: digest}}},
+				},
+			},
+		},
+	} {
+		if err := streams.Add(stream); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, err := NewPlanner(listersimagev1.NewImageStreamLister(streams), nil, nil, Options{Registry: "mirror.example.com"}).Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Mapping{
+		{Source: "quay.io/org/app@" + digest, Destination: "mirror.example.com/org/app", Tags: []string{"ns/app:external"}},
+		{Source: "quay.io/org/upstream:1", Destination: "mirror.example.com/org/upstream:1", Tags: []string{"ns/app:upstream"}},
+	}
+	if !reflect.DeepEqual(plan.Mappings, want) {
+		t.Errorf("expected mappings %+v, got %+v", want, plan.Mappings)
+	}
+}
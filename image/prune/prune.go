@@ -0,0 +1,428 @@
+// Package prune trims the tag history of image streams, keeping recent and referenced images.
+package prune
+
+import (
+	context "context"
+	fmt "fmt"
+	io "io"
+	sort "sort"
+	tabwriter "text/tabwriter"
+	time "time"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	listersappsv1 "github.com/openshift/client-go/apps/listers/apps/v1"
+	listersbuildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	strategy "github.com/openshift/client-go/build/strategy"
+	typedimagev1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	listersimagev1 "github.com/openshift/client-go/image/listers/image/v1"
+	reference "github.com/openshift/client-go/image/reference"
+	corev1 "k8s.io/api/core/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	retry "k8s.io/client-go/util/retry"
+)
+
+// Options configures which tag events are removed.
+type Options struct {
+	// Namespace restricts pruning to a single namespace, all namespaces when empty.
+	Namespace string
+	// KeepTagRevisions is the number of most recent tag events kept per tag. The current image
+	// of a tag is always kept.
+	KeepTagRevisions int
+	// KeepYoungerThan protects tag events created more recently than this duration.
+	KeepYoungerThan time.Duration
+}
+
+// DefaultOptions returns the options oc adm prune images uses by default.
+func DefaultOptions() Options {
+	return Options{
+		KeepTagRevisions: 3,
+		KeepYoungerThan:  60 * time.Minute,
+	}
+}
+
+// Referrers are the caches consulted for images in use. Images referenced by a pod, deployment
+// config, build or build config are never pruned, whether the reference is by digest or by tag.
+// Tag references, as image stream tags or as pull specs of an image stream repository, protect
+// the current image of the tag. Any of them may be nil.
+type Referrers struct {
+	Pods              listerscorev1.PodLister
+	DeploymentConfigs listersappsv1.DeploymentConfigLister
+	Builds            listersbuildv1.BuildLister
+	BuildConfigs      listersbuildv1.BuildConfigLister
+}
+
+// Candidate is a tag event selected for removal.
+type Candidate struct {
+	Namespace string
+	Stream    string
+	Tag       string
+	Event     imagev1.TagEvent
+	// Size is the sum of the layer sizes of the image, zero if the image is unknown.
+	Size int64
+}
+
+// Pruner computes and removes tag history prune candidates.
+type Pruner struct {
+	client    typedimagev1.ImageStreamsGetter
+	streams   listersimagev1.ImageStreamLister
+	images    listersimagev1.ImageLister
+	referrers Referrers
+	options   Options
+	now       func() time.Time
+}
+
+// NewPruner returns a Pruner that reads image streams and images through the given listers and
+// updates image streams through client. images may be nil, in which case sizes are not reported.
+func NewPruner(client typedimagev1.ImageStreamsGetter, streams listersimagev1.ImageStreamLister, images listersimagev1.ImageLister, referrers Referrers, options Options) *Pruner {
+	if options.KeepTagRevisions < 1 {
+		options.KeepTagRevisions = 1
+	}
+	return &Pruner{
+		client:    client,
+		streams:   streams,
+		images:    images,
+		referrers: referrers,
+		options:   options,
+		now:       time.Now,
+	}
+}
+
+// Candidates returns the tag events that should be removed, ordered by namespace, stream and tag,
+// newest first within a tag.
+func (p *Pruner) Candidates() ([]Candidate, error) {
+	var streams []*imagev1.ImageStream
+	var err error
+	if len(p.options.Namespace) > 0 {
+		streams, err = p.streams.ImageStreams(p.options.Namespace).List(labels.Everything())
+	} else {
+		streams, err = p.streams.List(labels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+	referenced, err := p.referencedImages()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(streams, func(i, j int) bool {
+		if streams[i].Namespace != streams[j].Namespace {
+			return streams[i].Namespace < streams[j].Namespace
+		}
+		return streams[i].Name < streams[j].Name
+	})
+	var candidates []Candidate
+	for _, stream := range streams {
+		for _, history := range stream.Status.Tags {
+			for i, event := range history.Items {
+				if i < p.options.KeepTagRevisions || p.isYoung(event) || referenced.Has(event.Image) {
+					continue
+				}
+				candidates = append(candidates, Candidate{
+					Namespace: stream.Namespace,
+					Stream:    stream.Name,
+					Tag:       history.Tag,
+					Event:     event,
+					Size:      p.sizeOf(event.Image),
+				})
+			}
+		}
+	}
+	return candidates, nil
+}
+
+func (p *Pruner) isYoung(event imagev1.TagEvent) bool {
+	return p.now().Sub(event.Created.Time) < p.options.KeepYoungerThan
+}
+
+// sizeOf returns the sum of the layer sizes of the named image.
+func (p *Pruner) sizeOf(name string) int64 {
+	if p.images == nil || len(name) == 0 {
+		return 0
+	}
+	image, err := p.images.Get(name)
+	if err != nil {
+		return 0
+	}
+	var size int64
+	for _, layer := range image.DockerImageLayers {
+		size += layer.LayerSize
+	}
+	return size
+}
+
+// referencedImages returns the digests of the images referenced by pods, deployment configs,
+// builds and build configs.
+func (p *Pruner) referencedImages() (imageSet, error) {
+	streams, err := p.streams.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	referenced := &references{
+		images:       imageSet{},
+		resolver:     reference.NewResolver(p.streams),
+		repositories: map[string]*imagev1.ImageStream{},
+	}
+	for _, stream := range streams {
+		for _, repository := range []string{stream.Status.DockerImageRepository, stream.Status.PublicDockerImageRepository} {
+			if ref, err := reference.Parse(repository); err == nil && len(repository) > 0 {
+				referenced.repositories[ref.AsRepository().Exact()] = stream
+			}
+		}
+	}
+
+	if p.referrers.Pods != nil {
+		var pods []*corev1.Pod
+		if len(p.options.Namespace) > 0 {
+			pods, err = p.referrers.Pods.Pods(p.options.Namespace).List(labels.Everything())
+		} else {
+			pods, err = p.referrers.Pods.List(labels.Everything())
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range pods {
+			referenced.addPodSpec(pod.Namespace, &pod.Spec)
+		}
+	}
+	if p.referrers.DeploymentConfigs != nil {
+		var configs []*appsv1.DeploymentConfig
+		if len(p.options.Namespace) > 0 {
+			configs, err = p.referrers.DeploymentConfigs.DeploymentConfigs(p.options.Namespace).List(labels.Everything())
+		} else {
+			configs, err = p.referrers.DeploymentConfigs.List(labels.Everything())
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, config := range configs {
+			if config.Spec.Template != nil {
+				referenced.addPodSpec(config.Namespace, &config.Spec.Template.Spec)
+			}
+			for _, trigger := range config.Spec.Triggers {
+				if trigger.ImageChangeParams != nil {
+					referenced.addPullSpec(trigger.ImageChangeParams.LastTriggeredImage)
+				}
+			}
+		}
+	}
+	if p.referrers.Builds != nil {
+		var builds []*buildv1.Build
+		if len(p.options.Namespace) > 0 {
+			builds, err = p.referrers.Builds.Builds(p.options.Namespace).List(labels.Everything())
+		} else {
+			builds, err = p.referrers.Builds.List(labels.Everything())
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, build := range builds {
+			referenced.addCommonSpec(build.Namespace, &build.Spec.CommonSpec)
+			if build.Status.Output.To != nil {
+				referenced.images.Insert(build.Status.Output.To.ImageDigest)
+			}
+		}
+	}
+	if p.referrers.BuildConfigs != nil {
+		var configs []*buildv1.BuildConfig
+		if len(p.options.Namespace) > 0 {
+			configs, err = p.referrers.BuildConfigs.BuildConfigs(p.options.Namespace).List(labels.Everything())
+		} else {
+			configs, err = p.referrers.BuildConfigs.List(labels.Everything())
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, config := range configs {
+			referenced.addCommonSpec(config.Namespace, &config.Spec.CommonSpec)
+		}
+	}
+	return referenced.images, nil
+}
+
+// Prune removes the prune candidates from the tag history of their image streams, or only reports
+// them when dryRun is true. Every candidate is written to out, which may be nil. It returns the
+// candidates and the first update error.
+func (p *Pruner) Prune(ctx context.Context, dryRun bool, out io.Writer) ([]Candidate, error) {
+	candidates, err := p.Candidates()
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = io.Discard
+	}
+	w := tabwriter.NewWriter(out, 10, 4, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tSTREAM\tTAG\tIMAGE\tCREATED\tSIZE")
+	for _, candidate := range candidates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n", candidate.Namespace, candidate.Stream, candidate.Tag,
+			candidate.Event.Image, candidate.Event.Created.UTC().Format(time.RFC3339), candidate.Size)
+	}
+	w.Flush()
+	if dryRun {
+		return candidates, nil
+	}
+
+	byStream := map[streamKey][]Candidate{}
+	var keys []streamKey
+	for _, candidate := range candidates {
+		key := streamKey{namespace: candidate.Namespace, name: candidate.Stream}
+		if _, ok := byStream[key]; !ok {
+			keys = append(keys, key)
+		}
+		byStream[key] = append(byStream[key], candidate)
+	}
+	var firstErr error
+	for _, key := range keys {
+		err := p.removeEvents(ctx, key, byStream[key])
+		if err != nil && !errors.IsNotFound(err) && firstErr == nil {
+			firstErr = fmt.Errorf("failed to prune image stream %s/%s: %w", key.namespace, key.name, err)
+		}
+	}
+	return candidates, firstErr
+}
+
+// removeEvents removes the tag events of candidates from the latest version of the image stream.
+// Events that are no longer present, or have become the current image of their tag, are skipped.
+func (p *Pruner) removeEvents(ctx context.Context, key streamKey, candidates []Candidate) error {
+	remove := map[eventKey]bool{}
+	for _, candidate := range candidates {
+		remove[eventKeyFor(candidate.Tag, candidate.Event)] = true
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		stream, err := p.client.ImageStreams(key.namespace).Get(ctx, key.name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		changed := false
+		for i := range stream.Status.Tags {
+			history := &stream.Status.Tags[i]
+			items := make([]imagev1.TagEvent, 0, len(history.Items))
+			for j, event := range history.Items {
+				if j > 0 && remove[eventKeyFor(history.Tag, event)] {
+					changed = true
+					continue
+				}
+				items = append(items, event)
+			}
+			history.Items = items
+		}
+		if !changed {
+			return nil
+		}
+		_, err = p.client.ImageStreams(key.namespace).UpdateStatus(ctx, stream, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+type streamKey struct {
+	namespace string
+	name      string
+}
+
+type eventKey struct {
+	tag     string
+	image   string
+	created time.Time
+}
+
+func eventKeyFor(tag string, event imagev1.TagEvent) eventKey {
+	return eventKey{tag: tag, image: event.Image, created: event.Created.Time.UTC()}
+}
+
+// imageSet holds image digests.
+type imageSet map[string]struct{}
+
+func (s imageSet) Insert(digest string) {
+	if len(digest) > 0 {
+		s[digest] = struct{}{}
+	}
+}
+
+func (s imageSet) Has(digest string) bool {
+	_, ok := s[digest]
+	return ok
+}
+
+// references collects the digests of referenced images, resolving tag references through the
+// status of the image streams.
+type references struct {
+	images   imageSet
+	resolver *reference.Resolver
+	// repositories are the image streams by the exact pull spec of their integrated registry and
+	// public repositories.
+	repositories map[string]*imagev1.ImageStream
+}
+
+func (r *references) addPodSpec(namespace string, spec *corev1.PodSpec) {
+	for _, container := range spec.InitContainers {
+		r.addContainerImage(namespace, container.Image)
+	}
+	for _, container := range spec.Containers {
+		r.addContainerImage(namespace, container.Image)
+	}
+	for _, container := range spec.EphemeralContainers {
+		r.addContainerImage(namespace, container.Image)
+	}
+}
+
+// addCommonSpec records the builder image and the image sources of a build or build config.
+func (r *references) addCommonSpec(namespace string, spec *buildv1.CommonSpec) {
+	if from := strategy.BuilderImage(&spec.Strategy); from != nil {
+		r.addObjectReference(namespace, *from)
+	}
+	for _, source := range spec.Source.Images {
+		r.addObjectReference(namespace, source.From)
+	}
+}
+
+// addContainerImage records the image of a container in namespace, resolving short names of
+// image streams with a local lookup policy the way the cluster does.
+func (r *references) addContainerImage(namespace, image string) {
+	if resolved, ok, err := r.resolver.ResolveLocal(namespace, image); err == nil && ok {
+		image = resolved
+	}
+	r.addPullSpec(image)
+}
+
+// addPullSpec records the digest of spec. A pull spec by tag of an image stream repository
+// records the current image of that tag.
+func (r *references) addPullSpec(spec string) {
+	ref, err := reference.Parse(spec)
+	if err != nil {
+		return
+	}
+	if len(ref.ID) > 0 {
+		r.images.Insert(ref.ID)
+		return
+	}
+	stream, ok := r.repositories[ref.AsRepository().Exact()]
+	if !ok {
+		return
+	}
+	tag := ref.DockerClientDefaults().Tag
+	for _, history := range stream.Status.Tags {
+		if history.Tag == tag && len(history.Items) > 0 {
+			r.images.Insert(history.Items[0].Image)
+		}
+	}
+}
+
+// addObjectReference records the image of an ImageStreamTag, ImageStreamImage or DockerImage
+// reference. References without a namespace are resolved in namespace.
+func (r *references) addObjectReference(namespace string, ref corev1.ObjectReference) {
+	switch ref.Kind {
+	case "ImageStreamImage":
+		if _, digest, err := reference.ParseImageStreamImageName(ref.Name); err == nil {
+			r.images.Insert(digest)
+		}
+	case "ImageStreamTag", "DockerImage":
+		if spec, err := r.resolver.ResolveObjectReference(ref, namespace); err == nil {
+			r.addPullSpec(spec)
+		}
+	}
+}
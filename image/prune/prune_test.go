@@ -0,0 +1,193 @@
+package prune
+
+import (
+	reflect "reflect"
+	strings "strings"
+	testing "testing"
+	time "time"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	listersbuildv1 "github.com/openshift/client-go/build/listers/build/v1"
+	listersimagev1 "github.com/openshift/client-go/image/listers/image/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+var (
+	now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	d1 = "sha256:" + strings.Repeat("1", 64)
+	d2 = "sha256:" + strings.Repeat("2", 64)
+	d3 = "sha256:" + strings.Repeat("3", 64)
+	d4 = "sha256:" + strings.Repeat("4", 64)
+)
+
+// app is the image stream every test prunes. latest has moved through d1 to d4, prod still points
+// at d2 and stable at d3, so only references to prod or stable can protect the history of latest.
+func app() *imagev1.ImageStream {
+	event := func(digest string, age time.Duration) imagev1.TagEvent {
+		return imagev1.TagEvent{
+			Created:              metav1.NewTime(now.Add(-age)),
+			DockerImageReference: "quay.io/org/app@" + digest,
+			Image:                digest,
+		}
+	}
+	return &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+		Spec:       imagev1.ImageStreamSpec{LookupPolicy: imagev1.ImageLookupPolicy{Local: true}},
+		Status: imagev1.ImageStreamStatus{
+			DockerImageRepository:       "image-registry.openshift-image-registry.svc:5000/ns/app",
+			PublicDockerImageRepository: "registry.apps.example.com/ns/app",
+			Tags: []imagev1.NamedTagEventList{
+				{Tag: "latest", Items: []imagev1.TagEvent{event(d4, 10*time.Minute), event(d3, 3*time.Hour), event(d2, 4*time.Hour), event(d1, 5*time.Hour)}},
+				{Tag: "prod", Items: []imagev1.TagEvent{event(d2, 4*time.Hour)}},
+				{Tag: "stable", Items: []imagev1.TagEvent{event(d3, 3*time.Hour)}},
+			},
+		},
+	}
+}
+
+func pod(namespace, image string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "pod"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "c", Image: image}}},
+	}
+}
+
+func buildConfig(namespace string, from corev1.ObjectReference, sources ...buildv1.ImageSource) *buildv1.BuildConfig {
+	return &buildv1.BuildConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "bc"},
+		Spec: buildv1.BuildConfigSpec{CommonSpec: buildv1.CommonSpec{
+			Source:   buildv1.BuildSource{Images: sources},
+			Strategy: buildv1.BuildStrategy{DockerStrategy: &buildv1.DockerBuildStrategy{From: &from}},
+		}},
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		name string
+		// keepYoungerThan defaults to zero, so that only the current image of a tag is kept by age.
+		keepYoungerThan time.Duration
+		pods            []runtime.Object
+		builds          []runtime.Object
+		buildConfigs    []runtime.Object
+		// want are the digests pruned from the history of latest.
+		want []string
+	}{
+		{
+			name: "nothing referenced",
+			want: []string{d3, d2, d1},
+		},
+		{
+			name:            "keep younger than",
+			keepYoungerThan: 4*time.Hour + 30*time.Minute,
+			want:            []string{d1},
+		},
+		{
+			name: "pod by digest",
+			pods: []runtime.Object{pod("other", "quay.io/org/app@"+d1)},
+			want: []string{d3, d2},
+		},
+		{
+			name: "pod by tag of the integrated registry repository",
+			pods: []runtime.Object{pod("other", "image-registry.openshift-image-registry.svc:5000/ns/app:prod")},
+			want: []string{d3, d1},
+		},
+		{
+			name: "pod by tag of the public repository",
+			pods: []runtime.Object{pod("other", "registry.apps.example.com/ns/app:stable")},
+			want: []string{d2, d1},
+		},
+		{
+			name: "pod by short name of a local lookup stream",
+			pods: []runtime.Object{pod("ns", "app:prod")},
+			want: []string{d3, d1},
+		},
+		{
+			name: "short name outside the namespace of the stream",
+			pods: []runtime.Object{pod("other", "app:prod")},
+			want: []string{d3, d2, d1},
+		},
+		{
+			name: "pod by tag of an unknown repository",
+			pods: []runtime.Object{pod("ns", "quay.io/org/app:prod")},
+			want: []string{d3, d2, d1},
+		},
+		{
+			name: "build config builder image by image stream tag",
+			buildConfigs: []runtime.Object{
+				buildConfig("ns", corev1.ObjectReference{Kind: "ImageStreamTag", Name: "app:prod"}),
+			},
+			want: []string{d3, d1},
+		},
+		{
+			name: "build config in another namespace",
+			buildConfigs: []runtime.Object{
+				buildConfig("ci", corev1.ObjectReference{Kind: "ImageStreamTag", Namespace: "ns", Name: "app:stable"}),
+			},
+			want: []string{d2, d1},
+		},
+		{
+			name: "build config image sources",
+			buildConfigs: []runtime.Object{
+				buildConfig("ns", corev1.ObjectReference{Kind: "DockerImage", Name: "registry.apps.example.com/ns/app:stable"},
+					buildv1.ImageSource{From: corev1.ObjectReference{Kind: "ImageStreamImage", Name: "app@" + d1}}),
+			},
+			want: []string{d2},
+		},
+		{
+			name: "build builder image by tag",
+			builds: []runtime.Object{&buildv1.Build{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "bc-1"},
+				Spec: buildv1.BuildSpec{CommonSpec: buildv1.CommonSpec{Strategy: buildv1.BuildStrategy{
+					SourceStrategy: &buildv1.SourceBuildStrategy{From: corev1.ObjectReference{Kind: "ImageStreamTag", Name: "app:prod"}},
+				}}},
+			}},
+			want: []string{d3, d1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streams := listersimagev1.NewImageStreamLister(indexerOf(t, app()))
+			referrers := Referrers{
+				Pods:         listerscorev1.NewPodLister(indexerOf(t, test.pods...)),
+				Builds:       listersbuildv1.NewBuildLister(indexerOf(t, test.builds...)),
+				BuildConfigs: listersbuildv1.NewBuildConfigLister(indexerOf(t, test.buildConfigs...)),
+			}
+			pruner := NewPruner(nil, streams, nil, referrers, Options{KeepTagRevisions: 1, KeepYoungerThan: test.keepYoungerThan})
+			pruner.now = func() time.Time { return now }
+
+			candidates, err := pruner.Candidates()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, candidate := range candidates {
+				if candidate.Tag != "latest" {
+					t.Errorf("unexpected candidate %s %s", candidate.Tag, candidate.Event.Image)
+					continue
+				}
+				got = append(got, candidate.Event.Image)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v to be pruned, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func indexerOf(t *testing.T, objects ...runtime.Object) cache.Indexer {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, obj := range objects {
+		if err := indexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return indexer
+}
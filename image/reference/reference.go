@@ -0,0 +1,177 @@
+// Package reference parses and normalizes image pull specs and resolves image stream references
+// to the pull specs the cluster would use for them.
+package reference
+
+import (
+	fmt "fmt"
+	regexp "regexp"
+	strings "strings"
+)
+
+const (
+	// DockerDefaultRegistry is the registry short pull specs refer to.
+	DockerDefaultRegistry = "docker.io"
+	// DockerDefaultNamespace is the namespace single component names refer to on DockerDefaultRegistry.
+	DockerDefaultNamespace = "library"
+	// DockerDefaultTag is the tag pull specs without a tag or digest refer to.
+	DockerDefaultTag = "latest"
+
+	// dockerLegacyRegistry is the historical name of DockerDefaultRegistry.
+	dockerLegacyRegistry = "index.docker.io"
+)
+
+var (
+	componentRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	tagRegexp       = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRegexp    = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]{32,}$`)
+)
+
+// DockerImageReference points to a container image.
+type DockerImageReference struct {
+	// Registry is the host, and optional port, of the registry. Empty for short pull specs.
+	Registry string
+	// Namespace is the first path component of the repository, if the repository has more than one.
+	Namespace string
+	// Name is the remaining path of the repository.
+	Name string
+	// Tag is the tag of the image, may be empty.
+	Tag string
+	// ID is the digest of the image, may be empty.
+	ID string
+}
+
+// Parse parses a pull spec of the form [registry/][namespace/]name[:tag][@digest].
+func Parse(spec string) (DockerImageReference, error) {
+	var ref DockerImageReference
+	if len(spec) == 0 {
+		return ref, fmt.Errorf("image reference must not be empty")
+	}
+
+	name := spec
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.ID = name[i+1:]
+		name = name[:i]
+		if !digestRegexp.MatchString(ref.ID) {
+			return ref, fmt.Errorf("invalid image reference %q: invalid digest %q", spec, ref.ID)
+		}
+	}
+	// a colon after the last slash separates the tag, earlier ones belong to a registry port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+		if !tagRegexp.MatchString(ref.Tag) {
+			return ref, fmt.Errorf("invalid image reference %q: invalid tag %q", spec, ref.Tag)
+		}
+	}
+
+	components := strings.Split(name, "/")
+	if len(components) > 1 && isRegistry(components[0]) {
+		ref.Registry = components[0]
+		components = components[1:]
+	}
+	for _, component := range components {
+		if !componentRegexp.MatchString(component) {
+			return ref, fmt.Errorf("invalid image reference %q: invalid repository component %q", spec, component)
+		}
+	}
+	switch len(components) {
+	case 1:
+		ref.Name = components[0]
+	default:
+		ref.Namespace = components[0]
+		ref.Name = strings.Join(components[1:], "/")
+	}
+	return ref, nil
+}
+
+// isRegistry returns true if the first component of a pull spec names a registry rather than a
+// namespace.
+func isRegistry(component string) bool {
+	return component == "localhost" || strings.ContainsAny(component, ".:")
+}
+
+// DockerClientDefaults returns the reference with the defaults a container runtime applies to
+// short pull specs: the default registry, the library namespace and the latest tag.
+func (r DockerImageReference) DockerClientDefaults() DockerImageReference {
+	if len(r.Registry) == 0 || r.Registry == dockerLegacyRegistry {
+		r.Registry = DockerDefaultRegistry
+	}
+	if r.Registry == DockerDefaultRegistry && len(r.Namespace) == 0 {
+		r.Namespace = DockerDefaultNamespace
+	}
+	if len(r.Tag) == 0 && len(r.ID) == 0 {
+		r.Tag = DockerDefaultTag
+	}
+	return r
+}
+
+// Equal returns true if both references point to the same image once client defaults are applied.
+func (r DockerImageReference) Equal(other DockerImageReference) bool {
+	return r.DockerClientDefaults() == other.DockerClientDefaults()
+}
+
+// AsRepository returns the reference without tag and digest.
+func (r DockerImageReference) AsRepository() DockerImageReference {
+	r.Tag = ""
+	r.ID = ""
+	return r
+}
+
+// MostSpecific returns the reference pointing to the digest if it has one, otherwise to the tag.
+func (r DockerImageReference) MostSpecific() DockerImageReference {
+	if len(r.ID) > 0 {
+		r.Tag = ""
+	}
+	return r
+}
+
+// RepositoryName returns the repository path without the registry.
+func (r DockerImageReference) RepositoryName() string {
+	if len(r.Namespace) == 0 {
+		return r.Name
+	}
+	return r.Namespace + "/" + r.Name
+}
+
+// NameString returns the repository path with tag and digest but without the registry.
+func (r DockerImageReference) NameString() string {
+	name := r.RepositoryName()
+	if len(r.Tag) > 0 {
+		name += ":" + r.Tag
+	}
+	if len(r.ID) > 0 {
+		name += "@" + r.ID
+	}
+	return name
+}
+
+// Exact returns the fully qualified pull spec of the reference, with client defaults applied.
+func (r DockerImageReference) Exact() string {
+	return r.DockerClientDefaults().String()
+}
+
+// String returns the pull spec of the reference as parsed.
+func (r DockerImageReference) String() string {
+	if len(r.Registry) == 0 {
+		return r.NameString()
+	}
+	return r.Registry + "/" + r.NameString()
+}
+
+// ParseImageStreamTagName splits a name of the form stream:tag.
+func ParseImageStreamTagName(name string) (stream, tag string, err error) {
+	parts := strings.Split(name, ":")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", fmt.Errorf("image stream tag name %q must have the form <stream>:<tag>", name)
+	}
+	return parts[0], parts[1], nil
+}
+
+// ParseImageStreamImageName splits a name of the form stream@digest.
+func ParseImageStreamImageName(name string) (stream, digest string, err error) {
+	parts := strings.Split(name, "@")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", fmt.Errorf("image stream image name %q must have the form <stream>@<digest>", name)
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,153 @@
+package reference
+
+import (
+	testing "testing"
+)
+
+const testDigest = "sha256:3c3e5a2c5e3a6e4bb1f9c6b4bca5b6cd7dfb2c6cb4e0d1f32c53bd8b8c1a9e0f"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    DockerImageReference
+		wantErr bool
+	}{
+		{
+			spec: "nginx",
+			want: DockerImageReference{Name: "nginx"},
+		},
+		{
+			spec: "library/nginx:1.25",
+			want: DockerImageReference{Namespace: "library", Name: "nginx", Tag: "1.25"},
+		},
+		{
+			spec: "quay.io/openshift/origin-cli:latest",
+			want: DockerImageReference{Registry: "quay.io", Namespace: "openshift", Name: "origin-cli", Tag: "latest"},
+		},
+		{
+			spec: "localhost/app",
+			want: DockerImageReference{Registry: "localhost", Name: "app"},
+		},
+		{
+			spec: "registry:5000/ns/app",
+			want: DockerImageReference{Registry: "registry:5000", Namespace: "ns", Name: "app"},
+		},
+		{
+			spec: "registry.example.com:5000/team/group/app:v1@" + testDigest,
+			want: DockerImageReference{Registry: "registry.example.com:5000", Namespace: "team", Name: "group/app", Tag: "v1", ID: testDigest},
+		},
+		{
+			spec: "quay.io/ns/app@" + testDigest,
+			want: DockerImageReference{Registry: "quay.io", Namespace: "ns", Name: "app", ID: testDigest},
+		},
+		{
+			// a single component before a slash is a namespace unless it looks like a host
+			spec: "openshift/cli",
+			want: DockerImageReference{Namespace: "openshift", Name: "cli"},
+		},
+		{spec: "", wantErr: true},
+		{spec: "Upper/case", wantErr: true},
+		{spec: "quay.io/ns/app:", wantErr: true},
+		{spec: "quay.io/ns/app:-tag", wantErr: true},
+		{spec: "quay.io/ns/app@sha256:short", wantErr: true},
+		{spec: "quay.io//app", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			got, err := Parse(test.spec)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %#v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("expected %#v, got %#v", test.want, got)
+			}
+			if got.String() != test.spec {
+				t.Errorf("expected String() to round trip to %q, got %q", test.spec, got.String())
+			}
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "nginx", b: "docker.io/library/nginx:latest", want: true},
+		{a: "index.docker.io/library/nginx", b: "nginx", want: true},
+		{a: "nginx:1.25", b: "nginx", want: false},
+		{a: "quay.io/nginx", b: "nginx", want: false},
+		{a: "quay.io/ns/app@" + testDigest, b: "quay.io/ns/app@" + testDigest, want: true},
+		{a: "quay.io/ns/app:v1", b: "quay.io/other/app:v1", want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.a+"="+test.b, func(t *testing.T) {
+			a, err := Parse(test.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Parse(test.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Equal(b); got != test.want {
+				t.Errorf("expected %t, got %t", test.want, got)
+			}
+		})
+	}
+}
+
+func TestExact(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{spec: "nginx", want: "docker.io/library/nginx:latest"},
+		{spec: "user/app:v1", want: "docker.io/user/app:v1"},
+		{spec: "quay.io/app", want: "quay.io/app:latest"},
+		{spec: "quay.io/ns/app@" + testDigest, want: "quay.io/ns/app@" + testDigest},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			ref, err := Parse(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ref.Exact(); got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParseImageStreamTagName(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantStream string
+		wantTag    string
+		wantErr    bool
+	}{
+		{name: "ruby:3.1", wantStream: "ruby", wantTag: "3.1"},
+		{name: "ruby", wantErr: true},
+		{name: "ruby:", wantErr: true},
+		{name: ":3.1", wantErr: true},
+		{name: "ruby:3:1", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream, tag, err := ParseImageStreamTagName(test.name)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %t, got %v", test.wantErr, err)
+			}
+			if stream != test.wantStream || tag != test.wantTag {
+				t.Errorf("expected %q %q, got %q %q", test.wantStream, test.wantTag, stream, tag)
+			}
+		})
+	}
+}
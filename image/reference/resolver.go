@@ -0,0 +1,156 @@
+package reference
+
+import (
+	fmt "fmt"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	listersimagev1 "github.com/openshift/client-go/image/listers/image/v1"
+	corev1 "k8s.io/api/core/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Resolver resolves image stream references to digest pull specs from an image stream cache.
+type Resolver struct {
+	streams listersimagev1.ImageStreamLister
+}
+
+// NewResolver returns a Resolver reading image streams from lister.
+func NewResolver(lister listersimagev1.ImageStreamLister) *Resolver {
+	return &Resolver{streams: lister}
+}
+
+// ResolveImageStreamTag returns the digest pull spec of the latest image of the tag name, of the
+// form stream:tag, honoring the reference policy of the tag.
+func (r *Resolver) ResolveImageStreamTag(namespace, name string) (string, error) {
+	streamName, tag, err := ParseImageStreamTagName(name)
+	if err != nil {
+		return "", err
+	}
+	stream, err := r.streams.ImageStreams(namespace).Get(streamName)
+	if err != nil {
+		return "", err
+	}
+	for _, history := range stream.Status.Tags {
+		if history.Tag != tag || len(history.Items) == 0 {
+			continue
+		}
+		return pullSpecFor(stream, tag, history.Items[0])
+	}
+	return "", errors.NewNotFound(imagev1.Resource("imagestreamtags"), name)
+}
+
+// ResolveImageStreamImage returns the digest pull spec of the image name, of the form
+// stream@digest, honoring the reference policy of the tag that references the image.
+func (r *Resolver) ResolveImageStreamImage(namespace, name string) (string, error) {
+	streamName, digest, err := ParseImageStreamImageName(name)
+	if err != nil {
+		return "", err
+	}
+	stream, err := r.streams.ImageStreams(namespace).Get(streamName)
+	if err != nil {
+		return "", err
+	}
+	// tag events are ordered newest first, so the most recent tagging of the image wins
+	for _, history := range stream.Status.Tags {
+		for _, event := range history.Items {
+			if event.Image == digest {
+				return pullSpecFor(stream, history.Tag, event)
+			}
+		}
+	}
+	return "", errors.NewNotFound(imagev1.Resource("imagestreamimages"), name)
+}
+
+// ResolveObjectReference resolves an ImageStreamTag, ImageStreamImage or DockerImage reference,
+// as used by builds and triggers, to a pull spec. References without a namespace are resolved in
+// defaultNamespace.
+func (r *Resolver) ResolveObjectReference(ref corev1.ObjectReference, defaultNamespace string) (string, error) {
+	namespace := ref.Namespace
+	if len(namespace) == 0 {
+		namespace = defaultNamespace
+	}
+	switch ref.Kind {
+	case "ImageStreamTag":
+		return r.ResolveImageStreamTag(namespace, ref.Name)
+	case "ImageStreamImage":
+		return r.ResolveImageStreamImage(namespace, ref.Name)
+	case "DockerImage":
+		if _, err := Parse(ref.Name); err != nil {
+			return "", err
+		}
+		return ref.Name, nil
+	default:
+		return "", fmt.Errorf("unsupported image reference kind %q", ref.Kind)
+	}
+}
+
+// ResolveLocal resolves a container image the way the cluster does for pods in namespace: a short
+// pull spec without registry and namespace that names an image stream with a local lookup policy
+// is replaced by the digest pull spec of the referenced tag or image. It returns false and no
+// error if the image is not resolved locally.
+func (r *Resolver) ResolveLocal(namespace, image string) (string, bool, error) {
+	ref, err := Parse(image)
+	if err != nil {
+		return "", false, err
+	}
+	if len(ref.Registry) > 0 || len(ref.Namespace) > 0 {
+		return "", false, nil
+	}
+	stream, err := r.streams.ImageStreams(namespace).Get(ref.Name)
+	if errors.IsNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if !stream.Spec.LookupPolicy.Local {
+		return "", false, nil
+	}
+
+	var resolved string
+	if len(ref.ID) > 0 {
+		resolved, err = r.ResolveImageStreamImage(namespace, ref.Name+"@"+ref.ID)
+	} else {
+		tag := ref.Tag
+		if len(tag) == 0 {
+			tag = DockerDefaultTag
+		}
+		resolved, err = r.ResolveImageStreamTag(namespace, ref.Name+":"+tag)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return resolved, true, nil
+}
+
+// pullSpecFor returns the pull spec of event: the integrated registry repository of the stream if
+// the tag uses the local reference policy and the stream has one, otherwise the source pull spec
+// recorded when the image was tagged.
+func pullSpecFor(stream *imagev1.ImageStream, tag string, event imagev1.TagEvent) (string, error) {
+	if referencePolicyFor(stream, tag) == imagev1.LocalTagReferencePolicy && len(stream.Status.DockerImageRepository) > 0 {
+		ref, err := Parse(stream.Status.DockerImageRepository)
+		if err != nil {
+			return "", err
+		}
+		ref.Tag = ""
+		ref.ID = event.Image
+		return ref.String(), nil
+	}
+	ref, err := Parse(event.DockerImageReference)
+	if err != nil {
+		return "", err
+	}
+	if len(event.Image) > 0 {
+		ref.ID = event.Image
+	}
+	return ref.MostSpecific().String(), nil
+}
+
+func referencePolicyFor(stream *imagev1.ImageStream, tag string) imagev1.TagReferencePolicyType {
+	for _, ref := range stream.Spec.Tags {
+		if ref.Name == tag {
+			return ref.ReferencePolicy.Type
+		}
+	}
+	return imagev1.SourceTagReferencePolicy
+}
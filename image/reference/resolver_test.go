@@ -0,0 +1,78 @@
+package reference
+
+import (
+	testing "testing"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	listersimagev1 "github.com/openshift/client-go/image/listers/image/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+func TestResolveImageStreamTag(t *testing.T) {
+	stream := func(policy imagev1.TagReferencePolicyType) *imagev1.ImageStream {
+		return &imagev1.ImageStream{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+			Spec: imagev1.ImageStreamSpec{
+				Tags: []imagev1.TagReference{{Name: "v1", ReferencePolicy: imagev1.TagReferencePolicy{Type: policy}}},
+			},
+			Status: imagev1.ImageStreamStatus{
+				DockerImageRepository: "image-registry.openshift-image-registry.svc:5000/ns/app",
+				Tags: []imagev1.NamedTagEventList{{
+					Tag: "v1",
+					Items: []imagev1.TagEvent{
+						{DockerImageReference: "quay.io/team/app:v1", Image: testDigest},
+						{DockerImageReference: "quay.io/team/app:v0", Image: "sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+					},
+				}},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		stream  *imagev1.ImageStream
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "source policy resolves to the digest of the latest event",
+			stream: stream(imagev1.SourceTagReferencePolicy),
+			tag:    "app:v1",
+			want:   "quay.io/team/app@" + testDigest,
+		},
+		{
+			name:   "local policy resolves through the integrated registry",
+			stream: stream(imagev1.LocalTagReferencePolicy),
+			tag:    "app:v1",
+			want:   "image-registry.openshift-image-registry.svc:5000/ns/app@" + testDigest,
+		},
+		{
+			name:    "missing tag",
+			stream:  stream(imagev1.SourceTagReferencePolicy),
+			tag:     "app:v2",
+			wantErr: true,
+		},
+		{
+			name:    "missing stream",
+			stream:  stream(imagev1.SourceTagReferencePolicy),
+			tag:     "other:v1",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			if err := indexer.Add(test.stream); err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewResolver(listersimagev1.NewImageStreamLister(indexer)).ResolveImageStreamTag("ns", test.tag)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %t, got %v", test.wantErr, err)
+			}
+			if got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
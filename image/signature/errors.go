@@ -0,0 +1,54 @@
+package signature
+
+import (
+	errors "errors"
+	fmt "fmt"
+)
+
+// Reason classifies why a signature was rejected.
+type Reason string
+
+const (
+	// ReasonUnsupportedType means the signature type is neither TypeAtomic nor TypeSigstore.
+	ReasonUnsupportedType Reason = "UnsupportedType"
+	// ReasonMalformed means the signature content or payload could not be decoded.
+	ReasonMalformed Reason = "Malformed"
+	// ReasonUnknownKey means the signature was not made by any of the trusted keys.
+	ReasonUnknownKey Reason = "UnknownKey"
+	// ReasonInvalidSignature means the signature does not match the signed content, or has expired.
+	ReasonInvalidSignature Reason = "InvalidSignature"
+	// ReasonKeyExpired means the signature was made by a trusted GPG key that has since expired.
+	ReasonKeyExpired Reason = "KeyExpired"
+	// ReasonKeyRevoked means the signature was made by a trusted GPG key that has been revoked.
+	ReasonKeyRevoked Reason = "KeyRevoked"
+	// ReasonDigestMismatch means the signature was made for another image.
+	ReasonDigestMismatch Reason = "DigestMismatch"
+	// ReasonIdentityMismatch means the signed identity does not match the expected pull spec.
+	ReasonIdentityMismatch Reason = "IdentityMismatch"
+	// ReasonNoSignatures means the image carries no signature that could be verified.
+	ReasonNoSignatures Reason = "NoSignatures"
+)
+
+// VerificationError describes why a signature, or an image, failed verification.
+type VerificationError struct {
+	// Signature is the name of the rejected signature, empty for errors about an image.
+	Signature string
+	Reason    Reason
+	Message   string
+}
+
+func (e *VerificationError) Error() string {
+	if len(e.Signature) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("signature %s: %s", e.Signature, e.Message)
+}
+
+// ReasonForError returns the reason of a *VerificationError, empty for other errors.
+func ReasonForError(err error) Reason {
+	var verificationErr *VerificationError
+	if errors.As(err, &verificationErr) {
+		return verificationErr.Reason
+	}
+	return ""
+}
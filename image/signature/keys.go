@@ -0,0 +1,48 @@
+package signature
+
+import (
+	bytes "bytes"
+	crypto "crypto"
+	x509 "crypto/x509"
+	pem "encoding/pem"
+	fmt "fmt"
+
+	openpgp "github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// ParseGPGKeys parses a key ring in ASCII armored or binary form.
+func ParseGPGKeys(data []byte) (openpgp.EntityList, error) {
+	keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse GPG keys: %w", err)
+	}
+	return keys, nil
+}
+
+// ParseSigstoreKeys parses the PEM encoded public keys in data, as written by cosign
+// generate-key-pair. ECDSA, RSA and Ed25519 keys are supported.
+func ParseSigstoreKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse sigstore public key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no PEM encoded public keys found")
+	}
+	return keys, nil
+}
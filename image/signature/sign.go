@@ -0,0 +1,110 @@
+package signature
+
+import (
+	bytes "bytes"
+	context "context"
+	crypto "crypto"
+	ed25519 "crypto/ed25519"
+	crand "crypto/rand"
+	sha256 "crypto/sha256"
+	base64 "encoding/base64"
+	json "encoding/json"
+	fmt "fmt"
+	time "time"
+
+	openpgp "github.com/ProtonMail/go-crypto/openpgp"
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	imagev1 "github.com/openshift/api/image/v1"
+	typedimagev1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Signer creates signatures of images.
+type Signer interface {
+	// Sign returns a signature of the image imageName, whose manifest digest is the image name,
+	// claiming identity as its pull spec. The signature is not stored.
+	Sign(imageName, identity string) (*imagev1.ImageSignature, error)
+}
+
+// NewGPGSigner returns a Signer creating atomic signatures with the private key of entity, which
+// must already be decrypted.
+func NewGPGSigner(entity *openpgp.Entity) Signer {
+	return &gpgSigner{entity: entity, now: time.Now}
+}
+
+// NewSigstoreSigner returns a Signer creating sigstore signatures with key, an ECDSA, RSA or
+// Ed25519 private key.
+func NewSigstoreSigner(key crypto.Signer) Signer {
+	return &sigstoreSigner{key: key, now: time.Now}
+}
+
+type gpgSigner struct {
+	entity *openpgp.Entity
+	now    func() time.Time
+}
+
+func (s *gpgSigner) Sign(imageName, identity string) (*imagev1.ImageSignature, error) {
+	now := s.now()
+	content, err := json.Marshal(newPayload(TypeAtomic, imageName, identity, now))
+	if err != nil {
+		return nil, err
+	}
+	// the OpenPGP signature carries the same creation time as the payload
+	var signed bytes.Buffer
+	w, err := openpgp.Sign(&signed, s.entity, nil, &packet.Config{Time: func() time.Time { return now }})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(content); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return newSignature(imageName, TypeAtomic, signed.Bytes(), nil)
+}
+
+type sigstoreSigner struct {
+	key crypto.Signer
+	now func() time.Time
+}
+
+func (s *sigstoreSigner) Sign(imageName, identity string) (*imagev1.ImageSignature, error) {
+	content, err := json.Marshal(newPayload(TypeSigstore, imageName, identity, s.now()))
+	if err != nil {
+		return nil, err
+	}
+	var raw []byte
+	if _, ok := s.key.Public().(ed25519.PublicKey); ok {
+		raw, err = s.key.Sign(crand.Reader, content, crypto.Hash(0))
+	} else {
+		sum := sha256.Sum256(content)
+		raw, err = s.key.Sign(crand.Reader, sum[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign image %s: %w", imageName, err)
+	}
+	annotations := map[string]string{SigstoreSignatureAnnotation: base64.StdEncoding.EncodeToString(raw)}
+	return newSignature(imageName, TypeSigstore, content, annotations)
+}
+
+func newSignature(imageName, signatureType string, content []byte, annotations map[string]string) (*imagev1.ImageSignature, error) {
+	name, err := Name(imageName)
+	if err != nil {
+		return nil, err
+	}
+	return &imagev1.ImageSignature{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Type:       signatureType,
+		Content:    content,
+	}, nil
+}
+
+// SignImage signs the image imageName with signer and stores the signature through client.
+func SignImage(ctx context.Context, client typedimagev1.ImageSignaturesGetter, signer Signer, imageName, identity string) (*imagev1.ImageSignature, error) {
+	signature, err := signer.Sign(imageName, identity)
+	if err != nil {
+		return nil, err
+	}
+	return client.ImageSignatures().Create(ctx, signature, metav1.CreateOptions{})
+}
@@ -0,0 +1,116 @@
+// Package signature verifies and creates image signatures stored as ImageSignature objects, using
+// GPG keys for atomic signatures or sigstore public keys, without contacting a registry or a
+// transparency log.
+//
+// GPG keys are checked for expiry and revocation at verification time, but only against the self
+// signatures and revocation signatures present in the trusted key ring; key servers are never
+// consulted, so a revocation that was not imported into the key ring is not detected. Sigstore
+// keys are bare public keys and carry no validity period or revocation status.
+package signature
+
+import (
+	crand "crypto/rand"
+	hex "encoding/hex"
+	json "encoding/json"
+	fmt "fmt"
+	time "time"
+)
+
+const (
+	// TypeAtomic is the ImageSignature type of GPG signed atomic container signatures. Their content
+	// is an OpenPGP signed message wrapping the signed payload.
+	TypeAtomic = "atomic"
+	// TypeSigstore is the ImageSignature type of sigstore signatures made with a public key. Their
+	// content is the signed payload and the signature is stored in SigstoreSignatureAnnotation.
+	TypeSigstore = "sigstore"
+
+	// SigstoreSignatureAnnotation holds the base64 encoded signature of a sigstore signature, the
+	// same annotation cosign uses on signature layers.
+	SigstoreSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+	// atomicPayloadType and sigstorePayloadType identify the payload format in the critical section.
+	atomicPayloadType   = "atomic container signature"
+	sigstorePayloadType = "cosign container image signature"
+)
+
+// Payload is the signed content of a simple signing signature, shared by atomic and sigstore
+// signatures.
+type Payload struct {
+	Critical Critical               `json:"critical"`
+	Optional map[string]interface{} `json:"optional,omitempty"`
+}
+
+// Critical holds the claims a verifier must check.
+type Critical struct {
+	Type     string   `json:"type"`
+	Image    Image    `json:"image"`
+	Identity Identity `json:"identity"`
+}
+
+// Image identifies the signed manifest.
+type Image struct {
+	DockerManifestDigest string `json:"docker-manifest-digest"`
+}
+
+// Identity is the pull spec the signer intended the image to be used as.
+type Identity struct {
+	DockerReference string `json:"docker-reference"`
+}
+
+// newPayload returns the payload signing digest as identity with a signature of the given type.
+func newPayload(signatureType, digest, identity string, now time.Time) Payload {
+	payloadType := atomicPayloadType
+	if signatureType == TypeSigstore {
+		payloadType = sigstorePayloadType
+	}
+	return Payload{
+		Critical: Critical{
+			Type:     payloadType,
+			Image:    Image{DockerManifestDigest: digest},
+			Identity: Identity{DockerReference: identity},
+		},
+		Optional: map[string]interface{}{
+			"creator":   "openshift client-go",
+			"timestamp": now.Unix(),
+		},
+	}
+}
+
+// parsePayload decodes content and checks that it has the payload type expected for signatureType.
+func parsePayload(signatureType string, content []byte) (*Payload, error) {
+	payload := &Payload{}
+	if err := json.Unmarshal(content, payload); err != nil {
+		return nil, &VerificationError{Reason: ReasonMalformed, Message: fmt.Sprintf("invalid signature payload: %v", err)}
+	}
+	expected := atomicPayloadType
+	if signatureType == TypeSigstore {
+		expected = sigstorePayloadType
+	}
+	if payload.Critical.Type != expected {
+		return nil, &VerificationError{Reason: ReasonMalformed, Message: fmt.Sprintf("unexpected signature payload type %q", payload.Critical.Type)}
+	}
+	if len(payload.Critical.Image.DockerManifestDigest) == 0 {
+		return nil, &VerificationError{Reason: ReasonMalformed, Message: "signature payload has no manifest digest"}
+	}
+	return payload, nil
+}
+
+// created returns the signing time recorded in the optional section, if any.
+func (p *Payload) created() *time.Time {
+	timestamp, ok := p.Optional["timestamp"].(float64)
+	if !ok {
+		return nil
+	}
+	created := time.Unix(int64(timestamp), 0).UTC()
+	return &created
+}
+
+// Name returns a new name for a signature of the image imageName, of the form
+// <image name>@<random suffix> the API server requires.
+func Name(imageName string) (string, error) {
+	suffix := make([]byte, 16)
+	if _, err := crand.Read(suffix); err != nil {
+		return "", err
+	}
+	return imageName + "@" + hex.EncodeToString(suffix), nil
+}
@@ -0,0 +1,218 @@
+package signature
+
+import (
+	bytes "bytes"
+	crypto "crypto"
+	ecdsa "crypto/ecdsa"
+	ed25519 "crypto/ed25519"
+	elliptic "crypto/elliptic"
+	crand "crypto/rand"
+	x509 "crypto/x509"
+	pem "encoding/pem"
+	strings "strings"
+	testing "testing"
+	time "time"
+
+	openpgp "github.com/ProtonMail/go-crypto/openpgp"
+	packet "github.com/ProtonMail/go-crypto/openpgp/packet"
+	imagev1 "github.com/openshift/api/image/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	digest   = "sha256:4a5f9c4c6b4e1e5e6bbd8f6c0d3c1f8a2e7b9d0c3a6f1e4b7c2d5a8f3e6b9c1d"
+	identity = "quay.io/org/app:v1"
+)
+
+// newGPGKey generates a fast EdDSA key created at created, valid for lifetime when it is not zero.
+func newGPGKey(t *testing.T, created time.Time, lifetime time.Duration) *openpgp.Entity {
+	t.Helper()
+	entity, err := openpgp.NewEntity("signer", "", "signer@example.com", &packet.Config{
+		Algorithm:       packet.PubKeyAlgoEdDSA,
+		Time:            func() time.Time { return created },
+		KeyLifetimeSecs: uint32(lifetime.Seconds()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entity
+}
+
+// publicKeyRing returns the public part of entity, as an administrator would distribute it.
+func publicKeyRing(t *testing.T, entity *openpgp.Entity) openpgp.EntityList {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := entity.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseGPGKeys(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// publicKeys returns the PEM encoded public keys of signers parsed back with ParseSigstoreKeys.
+func publicKeys(t *testing.T, signers ...crypto.Signer) []crypto.PublicKey {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, signer := range signers {
+		der, err := x509.MarshalPKIXPublicKey(signer.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := pem.Encode(&buf, &pem.Block{Type: "PUBLIC KEY", Bytes: der}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys, err := ParseSigstoreKeys(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	gpgKey := newGPGKey(t, now.Add(-time.Hour), 0)
+	otherGPGKey := newGPGKey(t, now.Add(-time.Hour), 0)
+	// expired an hour ago, but valid when it signed
+	expiredGPGKey := newGPGKey(t, now.Add(-3*time.Hour), 2*time.Hour)
+	revokedGPGKey := newGPGKey(t, now.Add(-time.Hour), 0)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed25519Key, err := ed25519.GenerateKey(crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherECDSAKey, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(signer Signer, imageName, identity string) *imagev1.ImageSignature {
+		t.Helper()
+		signature, err := signer.Sign(imageName, identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+	atomic := sign(NewGPGSigner(gpgKey), digest, identity)
+	expired := sign(&gpgSigner{entity: expiredGPGKey, now: func() time.Time { return now.Add(-2 * time.Hour) }}, digest, identity)
+	revoked := sign(NewGPGSigner(revokedGPGKey), digest, identity)
+	beforeRevocation := publicKeyRing(t, revokedGPGKey)
+	if err := revokedGPGKey.RevokeKey(packet.KeyCompromised, "leaked", nil); err != nil {
+		t.Fatal(err)
+	}
+	sigstore := sign(NewSigstoreSigner(ecdsaKey), digest, identity)
+
+	tamperedPayload := sigstore.DeepCopy()
+	tamperedPayload.Content = bytes.Replace(tamperedPayload.Content, []byte(digest), []byte(strings.Replace(digest, "4a5f", "0000", 1)), 1)
+	tamperedSignature := sigstore.DeepCopy()
+	tamperedSignature.Annotations[SigstoreSignatureAnnotation] = "not base64!"
+	tamperedMessage := atomic.DeepCopy()
+	tamperedMessage.Content = tamperedMessage.Content[:len(tamperedMessage.Content)/2]
+
+	verifier := NewVerifier(
+		append(append(publicKeyRing(t, gpgKey), publicKeyRing(t, expiredGPGKey)...), beforeRevocation...),
+		publicKeys(t, otherECDSAKey, ecdsaKey, ed25519Key),
+	)
+
+	tests := []struct {
+		name      string
+		signature *imagev1.ImageSignature
+		verifier  *Verifier
+		digest    string
+		identity  string
+		match     IdentityMatch
+		// want is the reason the signature is rejected, empty if it is accepted.
+		want Reason
+	}{
+		{name: "atomic", signature: atomic, identity: identity},
+		{name: "sigstore ecdsa", signature: sigstore, identity: "quay.io/org/app:v1"},
+		{name: "sigstore ed25519", signature: sign(NewSigstoreSigner(ed25519Key), digest, identity), identity: identity},
+		{name: "client defaults", signature: sign(NewSigstoreSigner(ecdsaKey), digest, "docker.io/library/busybox:latest"), identity: "busybox"},
+
+		{name: "atomic wrong key", signature: atomic, verifier: NewVerifier(publicKeyRing(t, otherGPGKey), nil), identity: identity, want: ReasonUnknownKey},
+		{name: "sigstore wrong key", signature: sigstore, verifier: NewVerifier(nil, publicKeys(t, otherECDSAKey)), identity: identity, want: ReasonUnknownKey},
+		{name: "expired key", signature: expired, identity: identity, want: ReasonKeyExpired},
+		{name: "revoked key", signature: revoked, verifier: NewVerifier(publicKeyRing(t, revokedGPGKey), nil), identity: identity, want: ReasonKeyRevoked},
+		// the key ring was exported before the revocation
+		{name: "revocation not imported", signature: revoked, identity: identity},
+
+		{name: "atomic digest mismatch", signature: atomic, digest: strings.Replace(digest, "4a5f", "ffff", 1), identity: identity, want: ReasonDigestMismatch},
+		{name: "sigstore digest mismatch", signature: sigstore, digest: strings.Replace(digest, "4a5f", "ffff", 1), identity: identity, want: ReasonDigestMismatch},
+
+		{name: "other tag", signature: atomic, identity: "quay.io/org/app:v2", want: ReasonIdentityMismatch},
+		{name: "other tag in repository", signature: atomic, identity: "quay.io/org/app:v2", match: MatchRepository},
+		{name: "other repository", signature: sigstore, identity: "quay.io/org/other:v1", match: MatchRepository, want: ReasonIdentityMismatch},
+		{name: "other registry", signature: sigstore, identity: "registry.example.com/org/app:v1", want: ReasonIdentityMismatch},
+		{name: "any identity", signature: sigstore, identity: "quay.io/org/other:v1", match: MatchAny},
+
+		{name: "tampered sigstore payload", signature: tamperedPayload, identity: identity, want: ReasonUnknownKey},
+		{name: "tampered sigstore signature", signature: tamperedSignature, identity: identity, want: ReasonMalformed},
+		{name: "truncated atomic message", signature: tamperedMessage, identity: identity, want: ReasonMalformed},
+		{name: "unsupported type", signature: &imagev1.ImageSignature{ObjectMeta: metav1.ObjectMeta{Name: digest + "@x509"}, Type: "x509"}, identity: identity, want: ReasonUnsupportedType},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := verifier
+			if test.verifier != nil {
+				v = test.verifier
+			}
+			expectedDigest := digest
+			if len(test.digest) > 0 {
+				expectedDigest = test.digest
+			}
+			result, err := v.Verify(test.signature, expectedDigest, test.identity, test.match)
+			if reason := ReasonForError(err); reason != test.want || (err != nil && len(reason) == 0) {
+				t.Fatalf("expected reason %q, got %v", test.want, err)
+			}
+			if err != nil {
+				if !strings.HasPrefix(err.Error(), "signature "+test.signature.Name) {
+					t.Errorf("expected the error to name the signature, got %v", err)
+				}
+				return
+			}
+			if result.Digest != digest || result.Signature != test.signature || result.Created == nil {
+				t.Errorf("unexpected result %#v", result)
+			}
+		})
+	}
+}
+
+func TestVerifyImage(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := &imagev1.Image{ObjectMeta: metav1.ObjectMeta{Name: digest}}
+	for _, signer := range []crypto.Signer{other, key} {
+		signature, err := NewSigstoreSigner(signer).Sign(digest, identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		image.Signatures = append(image.Signatures, *signature)
+	}
+
+	results, err := NewVerifier(nil, publicKeys(t, key)).VerifyImage(image, identity, MatchExact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Signature.Name != image.Signatures[1].Name || results[0].KeyID != "0" {
+		t.Errorf("expected only the second signature to verify, got %#v", results)
+	}
+
+	_, err = NewVerifier(nil, publicKeys(t, key)).VerifyImage(image, "quay.io/org/app:v2", MatchExact)
+	if ReasonForError(err) != ReasonNoSignatures || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected no valid signatures because of the identity, got %v", err)
+	}
+}
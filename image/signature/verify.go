@@ -0,0 +1,221 @@
+package signature
+
+import (
+	bytes "bytes"
+	crypto "crypto"
+	ecdsa "crypto/ecdsa"
+	ed25519 "crypto/ed25519"
+	rsa "crypto/rsa"
+	sha256 "crypto/sha256"
+	base64 "encoding/base64"
+	errors "errors"
+	fmt "fmt"
+	io "io"
+	time "time"
+
+	openpgp "github.com/ProtonMail/go-crypto/openpgp"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	imagev1 "github.com/openshift/api/image/v1"
+	reference "github.com/openshift/client-go/image/reference"
+)
+
+// IdentityMatch controls how the signed identity is compared with the expected pull spec.
+type IdentityMatch int
+
+const (
+	// MatchExact requires the signed identity to be the expected pull spec, after applying client
+	// defaults to both.
+	MatchExact IdentityMatch = iota
+	// MatchRepository requires the signed identity to be in the repository of the expected pull
+	// spec, with any tag.
+	MatchRepository
+	// MatchAny accepts any signed identity, only the digest is checked.
+	MatchAny
+)
+
+// Result describes a verified signature.
+type Result struct {
+	Signature *imagev1.ImageSignature
+	// Identity is the signed pull spec.
+	Identity string
+	// Digest is the signed manifest digest.
+	Digest string
+	// KeyID is the GPG key ID, or the index of the sigstore key, that verified the signature.
+	KeyID string
+	// Created is the signing time claimed by the signer, if recorded.
+	Created *time.Time
+}
+
+// Verifier verifies signatures against a fixed set of trusted keys.
+type Verifier struct {
+	gpgKeys      openpgp.EntityList
+	sigstoreKeys []crypto.PublicKey
+}
+
+// NewVerifier returns a Verifier trusting atomic signatures made by gpgKeys and sigstore
+// signatures made by sigstoreKeys. Either may be empty.
+func NewVerifier(gpgKeys openpgp.EntityList, sigstoreKeys []crypto.PublicKey) *Verifier {
+	return &Verifier{gpgKeys: gpgKeys, sigstoreKeys: sigstoreKeys}
+}
+
+// Verify verifies that signature was made by a trusted key for the manifest digest, and that the
+// signed identity matches identity according to match. It returns a *VerificationError if the
+// signature is rejected.
+func (v *Verifier) Verify(signature *imagev1.ImageSignature, digest, identity string, match IdentityMatch) (*Result, error) {
+	var result *Result
+	var err error
+	switch signature.Type {
+	case TypeAtomic:
+		result, err = v.verifyAtomic(signature)
+	case TypeSigstore:
+		result, err = v.verifySigstore(signature)
+	default:
+		err = &VerificationError{Reason: ReasonUnsupportedType, Message: fmt.Sprintf("unsupported signature type %q", signature.Type)}
+	}
+	if err == nil {
+		err = checkClaims(result, digest, identity, match)
+	}
+	if err != nil {
+		if verificationErr, ok := err.(*VerificationError); ok {
+			verificationErr.Signature = signature.Name
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// VerifyImage verifies the signatures embedded in image against its digest. It returns the
+// verified signatures, and a *VerificationError with ReasonNoSignatures if none was verified.
+func (v *Verifier) VerifyImage(image *imagev1.Image, identity string, match IdentityMatch) ([]Result, error) {
+	var results []Result
+	var errs []string
+	for i := range image.Signatures {
+		result, err := v.Verify(&image.Signatures[i], image.Name, identity, match)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		results = append(results, *result)
+	}
+	if len(results) == 0 {
+		message := fmt.Sprintf("image %s has no valid signature", image.Name)
+		if len(errs) > 0 {
+			message = fmt.Sprintf("%s: %v", message, errs)
+		}
+		return nil, &VerificationError{Reason: ReasonNoSignatures, Message: message}
+	}
+	return results, nil
+}
+
+func (v *Verifier) verifyAtomic(signature *imagev1.ImageSignature) (*Result, error) {
+	details, err := openpgp.ReadMessage(bytes.NewReader(signature.Content), v.gpgKeys, nil, nil)
+	if err != nil {
+		return nil, &VerificationError{Reason: ReasonMalformed, Message: fmt.Sprintf("invalid signed message: %v", err)}
+	}
+	if !details.IsSigned {
+		return nil, &VerificationError{Reason: ReasonMalformed, Message: "message is not signed"}
+	}
+	// the signature is only checked once the whole body has been read
+	content, err := io.ReadAll(details.UnverifiedBody)
+	if err != nil {
+		return nil, &VerificationError{Reason: ReasonMalformed, Message: fmt.Sprintf("invalid signed message: %v", err)}
+	}
+	if details.SignedBy == nil {
+		return nil, &VerificationError{Reason: ReasonUnknownKey, Message: fmt.Sprintf("signed by untrusted key %016X", details.SignedByKeyId)}
+	}
+	// the signature error also reports expiry and revocation of the signing key at the current time
+	switch err := details.SignatureError; {
+	case err == nil:
+	case errors.Is(err, pgperrors.ErrKeyExpired):
+		return nil, &VerificationError{Reason: ReasonKeyExpired, Message: fmt.Sprintf("signed by expired key %016X", details.SignedByKeyId)}
+	case errors.Is(err, pgperrors.ErrKeyRevoked):
+		return nil, &VerificationError{Reason: ReasonKeyRevoked, Message: fmt.Sprintf("signed by revoked key %016X", details.SignedByKeyId)}
+	default:
+		return nil, &VerificationError{Reason: ReasonInvalidSignature, Message: err.Error()}
+	}
+	if details.Signature == nil {
+		return nil, &VerificationError{Reason: ReasonInvalidSignature, Message: "unsupported signature packet"}
+	}
+
+	payload, err := parsePayload(TypeAtomic, content)
+	if err != nil {
+		return nil, err
+	}
+	return resultFor(signature, payload, fmt.Sprintf("%016X", details.SignedByKeyId)), nil
+}
+
+func (v *Verifier) verifySigstore(signature *imagev1.ImageSignature) (*Result, error) {
+	encoded, ok := signature.Annotations[SigstoreSignatureAnnotation]
+	if !ok {
+		return nil, &VerificationError{Reason: ReasonMalformed, Message: fmt.Sprintf("missing annotation %s", SigstoreSignatureAnnotation)}
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, &VerificationError{Reason: ReasonMalformed, Message: fmt.Sprintf("invalid signature encoding: %v", err)}
+	}
+	keyID := -1
+	for i, key := range v.sigstoreKeys {
+		if verifyRaw(key, signature.Content, raw) {
+			keyID = i
+			break
+		}
+	}
+	if keyID < 0 {
+		return nil, &VerificationError{Reason: ReasonUnknownKey, Message: "not signed by any trusted sigstore key"}
+	}
+
+	payload, err := parsePayload(TypeSigstore, signature.Content)
+	if err != nil {
+		return nil, err
+	}
+	return resultFor(signature, payload, fmt.Sprintf("%d", keyID)), nil
+}
+
+// verifyRaw returns true if sig is a signature of content made with the private key of key.
+func verifyRaw(key crypto.PublicKey, content, sig []byte) bool {
+	sum := sha256.Sum256(content)
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, sum[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, content, sig)
+	}
+	return false
+}
+
+func resultFor(signature *imagev1.ImageSignature, payload *Payload, keyID string) *Result {
+	return &Result{
+		Signature: signature,
+		Identity:  payload.Critical.Identity.DockerReference,
+		Digest:    payload.Critical.Image.DockerManifestDigest,
+		KeyID:     keyID,
+		Created:   payload.created(),
+	}
+}
+
+// checkClaims checks the digest and identity signed in result.
+func checkClaims(result *Result, digest, identity string, match IdentityMatch) error {
+	if result.Digest != digest {
+		return &VerificationError{Reason: ReasonDigestMismatch, Message: fmt.Sprintf("signed digest %s does not match %s", result.Digest, digest)}
+	}
+	if match == MatchAny {
+		return nil
+	}
+	signed, err := reference.Parse(result.Identity)
+	if err != nil {
+		return &VerificationError{Reason: ReasonMalformed, Message: fmt.Sprintf("invalid signed identity: %v", err)}
+	}
+	expected, err := reference.Parse(identity)
+	if err != nil {
+		return fmt.Errorf("invalid expected identity: %w", err)
+	}
+	if match == MatchRepository {
+		signed, expected = signed.AsRepository(), expected.AsRepository()
+	}
+	if !signed.Equal(expected) {
+		return &VerificationError{Reason: ReasonIdentityMismatch, Message: fmt.Sprintf("signed identity %s does not match %s", result.Identity, identity)}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+// Package tag points image stream tags at other tags, images or external pull specs, like oc tag.
+package tag
+
+import (
+	context "context"
+	fmt "fmt"
+	strings "strings"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	typedimagev1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	reference "github.com/openshift/client-go/image/reference"
+	corev1 "k8s.io/api/core/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	retry "k8s.io/client-go/util/retry"
+)
+
+// SourceKind is the kind of object a tag points to.
+type SourceKind string
+
+// Source kinds accepted by Tag, matching the kinds of TagReference.From.
+const (
+	SourceImageStreamTag   SourceKind = "ImageStreamTag"
+	SourceImageStreamImage SourceKind = "ImageStreamImage"
+	SourceDockerImage      SourceKind = "DockerImage"
+)
+
+// Source is what a tag is pointed at.
+type Source struct {
+	Kind SourceKind
+	// Namespace of the source image stream, ignored for DockerImage sources.
+	Namespace string
+	// Name is stream:tag, stream@digest or a pull spec depending on Kind.
+	Name string
+}
+
+// Target is the image stream tag being changed.
+type Target struct {
+	Namespace string
+	Stream    string
+	Tag       string
+}
+
+func (t Target) String() string {
+	return fmt.Sprintf("%s/%s:%s", t.Namespace, t.Stream, t.Tag)
+}
+
+// ParseSource parses an image stream tag of the form [namespace/]stream:tag, an image stream image
+// of the form [namespace/]stream@digest, or a pull spec, depending on kind. Image stream sources
+// without a namespace are in defaultNamespace.
+func ParseSource(kind SourceKind, spec, defaultNamespace string) (Source, error) {
+	source := Source{Kind: kind, Name: spec}
+	switch kind {
+	case SourceImageStreamTag, SourceImageStreamImage:
+		source.Namespace, source.Name = splitNamespace(spec, defaultNamespace)
+		var err error
+		if kind == SourceImageStreamTag {
+			_, _, err = reference.ParseImageStreamTagName(source.Name)
+		} else {
+			_, _, err = reference.ParseImageStreamImageName(source.Name)
+		}
+		if err != nil {
+			return source, err
+		}
+	case SourceDockerImage:
+		if _, err := reference.Parse(spec); err != nil {
+			return source, err
+		}
+	default:
+		return source, fmt.Errorf("unsupported source kind %q", kind)
+	}
+	return source, nil
+}
+
+// ParseTarget parses an image stream tag of the form [namespace/]stream[:tag]. The tag defaults
+// to latest and the namespace to defaultNamespace.
+func ParseTarget(spec, defaultNamespace string) (Target, error) {
+	namespace, name := splitNamespace(spec, defaultNamespace)
+	target := Target{Namespace: namespace, Stream: name, Tag: reference.DockerDefaultTag}
+	if i := strings.Index(name, ":"); i >= 0 {
+		target.Stream, target.Tag = name[:i], name[i+1:]
+	}
+	if len(target.Stream) == 0 || len(target.Tag) == 0 || strings.ContainsAny(target.Stream, "@/") || strings.ContainsAny(target.Tag, ":@/") {
+		return target, fmt.Errorf("image stream tag %q must have the form [<namespace>/]<stream>[:<tag>]", spec)
+	}
+	return target, nil
+}
+
+func splitNamespace(spec, defaultNamespace string) (string, string) {
+	if i := strings.Index(spec, "/"); i >= 0 {
+		return spec[:i], spec[i+1:]
+	}
+	return defaultNamespace, spec
+}
+
+// Options configures the tag reference written by Tag.
+type Options struct {
+	// Scheduled periodically re-imports DockerImage sources.
+	Scheduled bool
+	// Insecure allows importing DockerImage sources from registries without valid TLS certificates.
+	Insecure bool
+	// Reference only records the source, the image is not imported.
+	Reference bool
+	// ReferencePolicy controls how consumers pull the tagged image, Source when empty.
+	ReferencePolicy imagev1.TagReferencePolicyType
+	// ImportMode controls how manifest lists are imported.
+	ImportMode imagev1.ImportModeType
+}
+
+// Tagger changes image stream tags.
+type Tagger struct {
+	client typedimagev1.ImageV1Interface
+}
+
+// NewTagger returns a Tagger using client.
+func NewTagger(client typedimagev1.ImageV1Interface) *Tagger {
+	return &Tagger{client: client}
+}
+
+// Tag points target at the current image of source. ImageStreamTag sources are resolved to their
+// current image, so target does not follow later changes of the source tag; use Alias for that.
+// The target image stream is created if it does not exist.
+func (t *Tagger) Tag(ctx context.Context, source Source, target Target, options Options) (*imagev1.ImageStream, error) {
+	if options.Scheduled && source.Kind != SourceDockerImage {
+		return nil, fmt.Errorf("only DockerImage sources can be scheduled for import")
+	}
+	from := corev1.ObjectReference{Kind: string(source.Kind), Name: source.Name}
+	switch source.Kind {
+	case SourceDockerImage:
+		// pull specs are imported as is
+	case SourceImageStreamTag:
+		tag, err := t.client.ImageStreamTags(source.Namespace).Get(ctx, source.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		stream, _, _ := reference.ParseImageStreamTagName(source.Name)
+		from = corev1.ObjectReference{Kind: string(SourceImageStreamImage), Namespace: source.Namespace, Name: stream + "@" + tag.Image.Name}
+	case SourceImageStreamImage:
+		from.Namespace = source.Namespace
+	default:
+		return nil, fmt.Errorf("unsupported source kind %q", source.Kind)
+	}
+	if from.Namespace == target.Namespace {
+		from.Namespace = ""
+	}
+
+	policy := options.ReferencePolicy
+	if len(policy) == 0 {
+		policy = imagev1.SourceTagReferencePolicy
+	}
+	return t.setTag(ctx, target, imagev1.TagReference{
+		Name:      target.Tag,
+		From:      &from,
+		Reference: options.Reference,
+		ImportPolicy: imagev1.TagImportPolicy{
+			Scheduled:  options.Scheduled,
+			Insecure:   options.Insecure,
+			ImportMode: options.ImportMode,
+		},
+		ReferencePolicy: imagev1.TagReferencePolicy{Type: policy},
+	})
+}
+
+// Alias makes target track the ImageStreamTag source, so it follows every later change of the
+// source tag. The target image stream is created if it does not exist.
+func (t *Tagger) Alias(ctx context.Context, source Source, target Target) (*imagev1.ImageStream, error) {
+	if source.Kind != SourceImageStreamTag {
+		return nil, fmt.Errorf("only ImageStreamTag sources can be aliased")
+	}
+	stream, tag, err := reference.ParseImageStreamTagName(source.Name)
+	if err != nil {
+		return nil, err
+	}
+	if source.Namespace == target.Namespace && stream == target.Stream && tag == target.Tag {
+		return nil, fmt.Errorf("cannot alias %s to itself", target)
+	}
+	from := corev1.ObjectReference{Kind: string(SourceImageStreamTag), Namespace: source.Namespace, Name: source.Name}
+	if from.Namespace == target.Namespace {
+		from.Namespace = ""
+	}
+	return t.setTag(ctx, target, imagev1.TagReference{
+		Name:            target.Tag,
+		From:            &from,
+		ReferencePolicy: imagev1.TagReferencePolicy{Type: imagev1.SourceTagReferencePolicy},
+	})
+}
+
+// Untag removes target from its image stream, both the spec tag and its history. It deletes the
+// image tag rather than the image stream tag, because only the former also covers a spec tag
+// whose import has not produced an image yet.
+func (t *Tagger) Untag(ctx context.Context, target Target) error {
+	err := t.client.ImageTags(target.Namespace).Delete(ctx, target.Stream+":"+target.Tag, metav1.DeleteOptions{})
+	if !errors.IsNotFound(err) {
+		return err
+	}
+	// servers without the imagetags resource report it as not found too, so remove the spec tag from
+	// the stream directly
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		stream, err := t.client.ImageStreams(target.Namespace).Get(ctx, target.Stream, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for i, ref := range stream.Spec.Tags {
+			if ref.Name != target.Tag {
+				continue
+			}
+			stream.Spec.Tags = append(stream.Spec.Tags[:i], stream.Spec.Tags[i+1:]...)
+			_, err = t.client.ImageStreams(target.Namespace).Update(ctx, stream, metav1.UpdateOptions{})
+			return err
+		}
+		return errors.NewNotFound(imagev1.Resource("imagetags"), target.Stream+":"+target.Tag)
+	})
+}
+
+// setTag sets the spec tag ref on the target image stream, creating the stream if necessary. A
+// concurrent creation of the stream is retried like an update conflict.
+func (t *Tagger) setTag(ctx context.Context, target Target, ref imagev1.TagReference) (*imagev1.ImageStream, error) {
+	var result *imagev1.ImageStream
+	retriable := func(err error) bool {
+		return errors.IsConflict(err) || errors.IsAlreadyExists(err)
+	}
+	err := retry.OnError(retry.DefaultRetry, retriable, func() error {
+		streams := t.client.ImageStreams(target.Namespace)
+		stream, err := streams.Get(ctx, target.Stream, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			stream = &imagev1.ImageStream{
+				ObjectMeta: metav1.ObjectMeta{Namespace: target.Namespace, Name: target.Stream},
+				Spec:       imagev1.ImageStreamSpec{Tags: []imagev1.TagReference{ref}},
+			}
+			result, err = streams.Create(ctx, stream, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		replaced := false
+		for i := range stream.Spec.Tags {
+			if stream.Spec.Tags[i].Name == target.Tag {
+				// keep the annotations of the existing tag, the generation is set by the server
+				ref.Annotations = stream.Spec.Tags[i].Annotations
+				stream.Spec.Tags[i] = ref
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			stream.Spec.Tags = append(stream.Spec.Tags, ref)
+		}
+		result, err = streams.Update(ctx, stream, metav1.UpdateOptions{})
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,251 @@
+package tag
+
+import (
+	context "context"
+	reflect "reflect"
+	strings "strings"
+	testing "testing"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	fake "github.com/openshift/client-go/image/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+const digest = "sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4"
+
+// calls renders the mutating requests client received as "verb resource[/subresource]".
+func calls(client *fake.Clientset) []string {
+	var rendered []string
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "get" {
+			continue
+		}
+		call := action.GetVerb() + " " + action.GetResource().Resource
+		if len(action.GetSubresource()) > 0 {
+			call += "/" + action.GetSubresource()
+		}
+		rendered = append(rendered, call)
+	}
+	return rendered
+}
+
+func specTag(t *testing.T, client *fake.Clientset, namespace, stream, tag string) *imagev1.TagReference {
+	t.Helper()
+	is, err := client.ImageV1().ImageStreams(namespace).Get(context.TODO(), stream, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range is.Spec.Tags {
+		if is.Spec.Tags[i].Name == tag {
+			return &is.Spec.Tags[i]
+		}
+	}
+	return nil
+}
+
+func TestParse(t *testing.T) {
+	source, err := ParseSource(SourceImageStreamTag, "other/src:1", "ns")
+	if err != nil || source != (Source{Kind: SourceImageStreamTag, Namespace: "other", Name: "src:1"}) {
+		t.Errorf("unexpected source %+v: %v", source, err)
+	}
+	source, err = ParseSource(SourceImageStreamImage, "src@"+digest, "ns")
+	if err != nil || source.Namespace != "ns" {
+		t.Errorf("expected the default namespace, got %+v: %v", source, err)
+	}
+	source, err = ParseSource(SourceDockerImage, "quay.io/org/app:1", "ns")
+	if err != nil || source.Namespace != "" || source.Name != "quay.io/org/app:1" {
+		t.Errorf("expected the pull spec unchanged, got %+v: %v", source, err)
+	}
+	for kind, spec := range map[SourceKind]string{
+		SourceImageStreamTag:   "src",
+		SourceImageStreamImage: "src:1",
+		SourceDockerImage:      "quay.io/org/App",
+		"Pod":                  "src",
+	} {
+		if _, err := ParseSource(kind, spec, "ns"); err == nil {
+			t.Errorf("expected %s %q to be rejected", kind, spec)
+		}
+	}
+
+	target, err := ParseTarget("app", "ns")
+	if err != nil || target.String() != "ns/app:latest" {
+		t.Errorf("unexpected target %s: %v", target, err)
+	}
+	target, err = ParseTarget("other/app:prod", "ns")
+	if err != nil || target.String() != "other/app:prod" {
+		t.Errorf("unexpected target %s: %v", target, err)
+	}
+	for _, spec := range []string{":prod", "app:", "app:a:b", "app@" + digest} {
+		if _, err := ParseTarget(spec, "ns"); err == nil {
+			t.Errorf("expected target %q to be rejected", spec)
+		}
+	}
+}
+
+func TestTag(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&imagev1.ImageStreamTag{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "src:1"}, Image: imagev1.Image{ObjectMeta: metav1.ObjectMeta{Name: digest}}},
+		&imagev1.ImageStream{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+			Spec: imagev1.ImageStreamSpec{Tags: []imagev1.TagReference{
+				{Name: "prod", Annotations: map[string]string{"description": "production"}, From: &corev1.ObjectReference{Kind: "DockerImage", Name: "quay.io/org/app:0"}},
+			}},
+		},
+	)
+	tagger := NewTagger(client.ImageV1())
+	ctx := context.TODO()
+
+	// an image stream tag is resolved to its current image, in the source namespace
+	source, _ := ParseSource(SourceImageStreamTag, "other/src:1", "ns")
+	if _, err := tagger.Tag(ctx, source, Target{Namespace: "ns", Stream: "app", Tag: "prod"}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	prod := specTag(t, client, "ns", "app", "prod")
+	if want := (corev1.ObjectReference{Kind: "ImageStreamImage", Namespace: "other", Name: "src@" + digest}); *prod.From != want {
+		t.Errorf("expected prod to point at %v, got %v", want, prod.From)
+	}
+	if prod.Annotations["description"] != "production" || prod.ReferencePolicy.Type != imagev1.SourceTagReferencePolicy {
+		t.Errorf("expected the annotations to be kept and the source policy, got %+v", prod)
+	}
+
+	// a pull spec is recorded as is, in a stream created for it
+	source, _ = ParseSource(SourceDockerImage, "quay.io/org/tools:1", "ns")
+	options := Options{Scheduled: true, Insecure: true, ReferencePolicy: imagev1.LocalTagReferencePolicy, ImportMode: imagev1.ImportModePreserveOriginal}
+	if _, err := tagger.Tag(ctx, source, Target{Namespace: "ns", Stream: "tools", Tag: "1"}, options); err != nil {
+		t.Fatal(err)
+	}
+	tools := specTag(t, client, "ns", "tools", "1")
+	if want := (imagev1.TagImportPolicy{Scheduled: true, Insecure: true, ImportMode: imagev1.ImportModePreserveOriginal}); tools.ImportPolicy != want || tools.From.Name != "quay.io/org/tools:1" || tools.ReferencePolicy.Type != imagev1.LocalTagReferencePolicy {
+		t.Errorf("unexpected tag %+v", tools)
+	}
+
+	// an image stream image in the target namespace is referenced without namespace
+	source, _ = ParseSource(SourceImageStreamImage, "app@"+digest, "ns")
+	if _, err := tagger.Tag(ctx, source, Target{Namespace: "ns", Stream: "app", Tag: "pinned"}, Options{Reference: true}); err != nil {
+		t.Fatal(err)
+	}
+	if pinned := specTag(t, client, "ns", "app", "pinned"); pinned.From.Namespace != "" || !pinned.Reference {
+		t.Errorf("unexpected tag %+v", pinned)
+	}
+
+	if want := []string{"update imagestreams", "create imagestreams", "update imagestreams"}; !reflect.DeepEqual(calls(client), want) {
+		t.Errorf("expected requests %v, got %v", want, calls(client))
+	}
+
+	if _, err := tagger.Tag(ctx, Source{Kind: SourceImageStreamTag, Namespace: "other", Name: "src:1"}, Target{Namespace: "ns", Stream: "app", Tag: "x"}, Options{Scheduled: true}); err == nil {
+		t.Errorf("expected scheduling an image stream tag to fail")
+	}
+	if _, err := tagger.Tag(ctx, Source{Kind: SourceImageStreamTag, Namespace: "other", Name: "src:2"}, Target{Namespace: "ns", Stream: "app", Tag: "x"}, Options{}); !errors.IsNotFound(err) {
+		t.Errorf("expected a missing source tag to be reported, got %v", err)
+	}
+}
+
+func TestTagRetries(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	// another client creates the stream, then updates it, just before each of our writes
+	conflicts := map[string]error{
+		"create": errors.NewAlreadyExists(imagev1.Resource("imagestreams"), "app"),
+		"update": errors.NewConflict(imagev1.Resource("imagestreams"), "app", nil),
+	}
+	client.PrependReactor("*", "imagestreams", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		err, ok := conflicts[action.GetVerb()]
+		if !ok {
+			return false, nil, nil
+		}
+		delete(conflicts, action.GetVerb())
+		if action.GetVerb() == "create" {
+			if err := client.Tracker().Add(&imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return true, nil, err
+	})
+
+	source, _ := ParseSource(SourceDockerImage, "quay.io/org/app:1", "ns")
+	if _, err := NewTagger(client.ImageV1()).Tag(context.TODO(), source, Target{Namespace: "ns", Stream: "app", Tag: "latest"}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"create imagestreams", "update imagestreams", "update imagestreams"}; !reflect.DeepEqual(calls(client), want) {
+		t.Errorf("expected requests %v, got %v", want, calls(client))
+	}
+	if specTag(t, client, "ns", "app", "latest") == nil {
+		t.Errorf("expected the tag to be set")
+	}
+}
+
+func TestAlias(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	tagger := NewTagger(client.ImageV1())
+
+	source, _ := ParseSource(SourceImageStreamTag, "app:latest", "ns")
+	if _, err := tagger.Alias(context.TODO(), source, Target{Namespace: "ns", Stream: "app", Tag: "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if from := specTag(t, client, "ns", "app", "prod").From; *from != (corev1.ObjectReference{Kind: "ImageStreamTag", Name: "app:latest"}) {
+		t.Errorf("expected prod to track app:latest, got %v", from)
+	}
+
+	for _, source := range []Source{
+		{Kind: SourceImageStreamTag, Namespace: "ns", Name: "app:latest"},
+		{Kind: SourceDockerImage, Name: "quay.io/org/app:1"},
+	} {
+		if _, err := tagger.Alias(context.TODO(), source, Target{Namespace: "ns", Stream: "app", Tag: "latest"}); err == nil {
+			t.Errorf("expected aliasing %+v to ns/app:latest to fail", source)
+		}
+	}
+}
+
+func TestUntag(t *testing.T) {
+	newStream := func() *imagev1.ImageStream {
+		return &imagev1.ImageStream{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+			Spec: imagev1.ImageStreamSpec{Tags: []imagev1.TagReference{
+				{Name: "latest"},
+				{Name: "pending"},
+			}},
+		}
+	}
+	tests := []struct {
+		name string
+		// imageTags serves the imagetags resource, deleting spec tags from the stored stream.
+		imageTags bool
+		tag       string
+		want      []string
+		wantErr   bool
+	}{
+		{name: "image tag", imageTags: true, tag: "pending", want: []string{"delete imagetags"}},
+		{name: "server without image tags", tag: "pending", want: []string{"delete imagetags", "update imagestreams"}},
+		{name: "missing tag", tag: "other", want: []string{"delete imagetags"}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(newStream())
+			if test.imageTags {
+				client.PrependReactor("delete", "imagetags", func(action clienttesting.Action) (bool, runtime.Object, error) {
+					_, tag, _ := strings.Cut(action.(clienttesting.DeleteAction).GetName(), ":")
+					stream := newStream()
+					stream.Spec.Tags = []imagev1.TagReference{{Name: "latest"}}
+					if tag != "pending" {
+						return true, nil, errors.NewNotFound(imagev1.Resource("imagetags"), action.(clienttesting.DeleteAction).GetName())
+					}
+					return true, nil, client.Tracker().Update(imagev1.SchemeGroupVersion.WithResource("imagestreams"), stream, "ns")
+				})
+			}
+
+			err := NewTagger(client.ImageV1()).Untag(context.TODO(), Target{Namespace: "ns", Stream: "app", Tag: test.tag})
+			if test.wantErr != errors.IsNotFound(err) || (err != nil && !test.wantErr) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(calls(client), test.want) {
+				t.Errorf("expected requests %v, got %v", test.want, calls(client))
+			}
+			if !test.wantErr && (specTag(t, client, "ns", "app", "pending") != nil || specTag(t, client, "ns", "app", "latest") == nil) {
+				t.Errorf("expected only pending to be removed")
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Package tagwatch turns image stream updates from an informer into tag level change events.
+package tagwatch
+
+import (
+	sort "sort"
+	sync "sync"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	informersimagev1 "github.com/openshift/client-go/image/informers/externalversions/image/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// Event is one of TagAdded, TagUpdated or TagRemoved.
+type Event interface {
+	// StreamName returns the namespace and name of the image stream of the tag.
+	StreamName() types.NamespacedName
+	// TagName returns the name of the changed tag.
+	TagName() string
+}
+
+// TagAdded is emitted when a tag gets its first image.
+type TagAdded struct {
+	Stream types.NamespacedName
+	Tag    string
+	Digest string
+}
+
+// TagUpdated is emitted when a tag points to a different image.
+type TagUpdated struct {
+	Stream    types.NamespacedName
+	Tag       string
+	OldDigest string
+	NewDigest string
+}
+
+// TagRemoved is emitted when a tag, or its image stream, is deleted.
+type TagRemoved struct {
+	Stream types.NamespacedName
+	Tag    string
+	// Digest is the last image the tag pointed to.
+	Digest string
+}
+
+func (e TagAdded) StreamName() types.NamespacedName   { return e.Stream }
+func (e TagAdded) TagName() string                    { return e.Tag }
+func (e TagUpdated) StreamName() types.NamespacedName { return e.Stream }
+func (e TagUpdated) TagName() string                  { return e.Tag }
+func (e TagRemoved) StreamName() types.NamespacedName { return e.Stream }
+func (e TagRemoved) TagName() string                  { return e.Tag }
+
+// Handler receives the events of a Notifier. It is called sequentially and should not block.
+type Handler func(Event)
+
+// Options configures a Notifier.
+type Options struct {
+	// IncludeInitial emits TagAdded for the tags of the image streams in the initial list of the
+	// informer, which are otherwise only recorded.
+	IncludeInitial bool
+}
+
+// Notifier emits an event for every tag whose current image changes. Events are computed against
+// the last state the Notifier observed rather than the old object of the informer, so resyncs
+// and repeated updates never produce duplicates.
+type Notifier struct {
+	informer     cache.SharedIndexInformer
+	registration cache.ResourceEventHandlerRegistration
+	handler      Handler
+	options      Options
+
+	lock sync.Mutex
+	// digests maps every known image stream to the current image of each of its tags.
+	digests map[types.NamespacedName]map[string]string
+}
+
+// NewNotifier registers a Notifier calling handler on informer. Call Stop to unregister it.
+func NewNotifier(informer informersimagev1.ImageStreamInformer, handler Handler, options Options) (*Notifier, error) {
+	n := &Notifier{
+		informer: informer.Informer(),
+		handler:  handler,
+		options:  options,
+		digests:  map[types.NamespacedName]map[string]string{},
+	}
+	registration, err := n.informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if stream, ok := obj.(*imagev1.ImageStream); ok {
+				n.sync(stream, !isInInitialList || n.options.IncludeInitial)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if stream, ok := newObj.(*imagev1.ImageStream); ok {
+				n.sync(stream, true)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if stream, ok := obj.(*imagev1.ImageStream); ok {
+				n.remove(types.NamespacedName{Namespace: stream.Namespace, Name: stream.Name})
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	n.registration = registration
+	return n, nil
+}
+
+// HasSynced returns true once the Notifier has observed the initial list of the informer.
+func (n *Notifier) HasSynced() bool {
+	return n.registration.HasSynced()
+}
+
+// Stop unregisters the Notifier from the informer.
+func (n *Notifier) Stop() error {
+	return n.informer.RemoveEventHandler(n.registration)
+}
+
+// sync records the current images of stream, emitting an event for every change since the last
+// observed state when notify is true.
+func (n *Notifier) sync(stream *imagev1.ImageStream, notify bool) {
+	key := types.NamespacedName{Namespace: stream.Namespace, Name: stream.Name}
+	current := currentDigests(stream)
+
+	n.lock.Lock()
+	previous := n.digests[key]
+	n.digests[key] = current
+	n.lock.Unlock()
+
+	if !notify {
+		return
+	}
+	for _, tag := range sortedTags(current) {
+		digest := current[tag]
+		old, ok := previous[tag]
+		switch {
+		case !ok:
+			n.handler(TagAdded{Stream: key, Tag: tag, Digest: digest})
+		case old != digest:
+			n.handler(TagUpdated{Stream: key, Tag: tag, OldDigest: old, NewDigest: digest})
+		}
+	}
+	for _, tag := range sortedTags(previous) {
+		if _, ok := current[tag]; !ok {
+			n.handler(TagRemoved{Stream: key, Tag: tag, Digest: previous[tag]})
+		}
+	}
+}
+
+// remove forgets a deleted image stream, emitting TagRemoved for each of its tags.
+func (n *Notifier) remove(key types.NamespacedName) {
+	n.lock.Lock()
+	previous := n.digests[key]
+	delete(n.digests, key)
+	n.lock.Unlock()
+
+	for _, tag := range sortedTags(previous) {
+		n.handler(TagRemoved{Stream: key, Tag: tag, Digest: previous[tag]})
+	}
+}
+
+// currentDigests returns the current image of every tag of stream that has one.
+func currentDigests(stream *imagev1.ImageStream) map[string]string {
+	digests := map[string]string{}
+	for _, history := range stream.Status.Tags {
+		if len(history.Items) > 0 && len(history.Items[0].Image) > 0 {
+			digests[history.Tag] = history.Items[0].Image
+		}
+	}
+	return digests
+}
+
+func sortedTags(digests map[string]string) []string {
+	tags := make([]string, 0, len(digests))
+	for tag := range digests {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// ForTag returns a Handler passing to handler only the events of the tag namespace/stream:tag.
+func ForTag(namespace, stream, tag string, handler Handler) Handler {
+	key := types.NamespacedName{Namespace: namespace, Name: stream}
+	return func(event Event) {
+		if event.StreamName() == key && event.TagName() == tag {
+			handler(event)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package tagwatch
+
+import (
+	context "context"
+	reflect "reflect"
+	testing "testing"
+	time "time"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	listersimagev1 "github.com/openshift/client-go/image/listers/image/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	types "k8s.io/apimachinery/pkg/types"
+	wait "k8s.io/apimachinery/pkg/util/wait"
+	watch "k8s.io/apimachinery/pkg/watch"
+	clientfeatures "k8s.io/client-go/features"
+	clientfeaturestesting "k8s.io/client-go/features/testing"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// informer serves a single list of initial streams, then whatever is sent on the fake watcher.
+type informer struct {
+	cache.SharedIndexInformer
+}
+
+func newInformer(watcher *watch.FakeWatcher, initial ...imagev1.ImageStream) informer {
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(context.Context, metav1.ListOptions) (runtime.Object, error) {
+			return &imagev1.ImageStreamList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}, Items: initial}, nil
+		},
+		WatchFuncWithContext: func(context.Context, metav1.ListOptions) (watch.Interface, error) {
+			return watcher, nil
+		},
+	}
+	return informer{cache.NewSharedIndexInformer(lw, &imagev1.ImageStream{}, 0, cache.Indexers{})}
+}
+
+func (i informer) Informer() cache.SharedIndexInformer { return i.SharedIndexInformer }
+
+func (i informer) Lister() listersimagev1.ImageStreamLister {
+	return listersimagev1.NewImageStreamLister(i.GetIndexer())
+}
+
+// imageStream returns namespace/name with the given current image per tag. A tag mapped to an
+// empty digest has a history without images.
+func imageStream(namespace, name string, tags map[string]string) *imagev1.ImageStream {
+	stream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	for tag, digest := range tags {
+		history := imagev1.NamedTagEventList{Tag: tag}
+		if len(digest) > 0 {
+			history.Items = []imagev1.TagEvent{{Image: digest}}
+		}
+		stream.Status.Tags = append(stream.Status.Tags, history)
+	}
+	return stream
+}
+
+// recorder collects the events passed to its handler.
+type recorder chan Event
+
+func (r recorder) handle(event Event) { r <- event }
+
+// expect waits for want, in order, and fails on any other event.
+func (r recorder) expect(t *testing.T, name string, want ...Event) {
+	t.Helper()
+	var got []Event
+	for len(got) < len(want) {
+		select {
+		case event := <-r:
+			got = append(got, event)
+		case <-time.After(wait.ForeverTestTimeout):
+			t.Fatalf("%s: timed out with events %v, expected %v", name, got, want)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: expected events %v, got %v", name, want, got)
+	}
+}
+
+// expectNone fails if an event arrives shortly.
+func (r recorder) expectNone(t *testing.T, name string) {
+	t.Helper()
+	select {
+	case event := <-r:
+		t.Errorf("%s: unexpected event %v", name, event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	clientfeaturestesting.SetFeatureDuringTest(t, clientfeatures.WatchListClient, false)
+
+	app := types.NamespacedName{Namespace: "ns", Name: "app"}
+	other := types.NamespacedName{Namespace: "ns", Name: "other"}
+	watcher := watch.NewFake()
+	inf := newInformer(watcher, *imageStream("ns", "app", map[string]string{"latest": "d1"}))
+
+	all, initial, latest := make(recorder, 10), make(recorder, 10), make(recorder, 10)
+	allNotifier, err := NewNotifier(inf, all.handle, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewNotifier(inf, initial.handle, Options{IncludeInitial: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewNotifier(inf, ForTag("ns", "app", "latest", latest.handle), Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go inf.RunWithContext(ctx)
+	if !cache.WaitForCacheSync(ctx.Done(), allNotifier.HasSynced) {
+		t.Fatal("notifier did not sync")
+	}
+	initial.expect(t, "initial list", TagAdded{Stream: app, Tag: "latest", Digest: "d1"})
+	all.expectNone(t, "initial list")
+
+	watcher.Modify(imageStream("ns", "app", map[string]string{"latest": "d2", "stable": "d1", "pending": ""}))
+	all.expect(t, "update",
+		TagUpdated{Stream: app, Tag: "latest", OldDigest: "d1", NewDigest: "d2"},
+		TagAdded{Stream: app, Tag: "stable", Digest: "d1"},
+	)
+	latest.expect(t, "update", TagUpdated{Stream: app, Tag: "latest", OldDigest: "d1", NewDigest: "d2"})
+
+	// the same state again, as a resync or an unrelated change would deliver it
+	watcher.Modify(imageStream("ns", "app", map[string]string{"latest": "d2", "stable": "d1", "pending": ""}))
+	all.expectNone(t, "unchanged")
+
+	watcher.Modify(imageStream("ns", "app", map[string]string{"latest": "d2"}))
+	all.expect(t, "tag removed", TagRemoved{Stream: app, Tag: "stable", Digest: "d1"})
+
+	watcher.Add(imageStream("ns", "other", map[string]string{"latest": "d3"}))
+	all.expect(t, "stream added", TagAdded{Stream: other, Tag: "latest", Digest: "d3"})
+
+	watcher.Delete(imageStream("ns", "app", map[string]string{"latest": "d2"}))
+	all.expect(t, "stream deleted", TagRemoved{Stream: app, Tag: "latest", Digest: "d2"})
+	latest.expect(t, "stream deleted", TagRemoved{Stream: app, Tag: "latest", Digest: "d2"})
+
+	if err := allNotifier.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Modify(imageStream("ns", "other", map[string]string{"latest": "d4"}))
+	// the notifiers still registered saw every change, including the last one
+	initial.expect(t, "after stop",
+		TagUpdated{Stream: app, Tag: "latest", OldDigest: "d1", NewDigest: "d2"},
+		TagAdded{Stream: app, Tag: "stable", Digest: "d1"},
+		TagRemoved{Stream: app, Tag: "stable", Digest: "d1"},
+		TagAdded{Stream: other, Tag: "latest", Digest: "d3"},
+		TagRemoved{Stream: app, Tag: "latest", Digest: "d2"},
+		TagUpdated{Stream: other, Tag: "latest", OldDigest: "d3", NewDigest: "d4"},
+	)
+	all.expectNone(t, "after stop")
+	latest.expectNone(t, "other stream")
+}
@@ -0,0 +1,174 @@
+// Package admission reports which routers admitted or rejected a route, and waits for a route to
+// be admitted.
+package admission
+
+import (
+	context "context"
+	fmt "fmt"
+	sort "sort"
+
+	routev1 "github.com/openshift/api/route/v1"
+	typedroutev1 "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fields "k8s.io/apimachinery/pkg/fields"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// RejectedError is returned when a router rejects a route.
+type RejectedError struct {
+	Namespace  string
+	Name       string
+	RouterName string
+	Reason     string
+	Message    string
+}
+
+func (e *RejectedError) Error() string {
+	msg := fmt.Sprintf("route %s/%s was rejected by router %s", e.Namespace, e.Name, e.RouterName)
+	if len(e.Reason) > 0 {
+		msg += fmt.Sprintf(": %s", e.Reason)
+	}
+	if len(e.Message) > 0 {
+		msg += fmt.Sprintf(": %s", e.Message)
+	}
+	return msg
+}
+
+// IsAdmitted returns true if the named router admitted the current host of route. An empty
+// routerName matches any router.
+func IsAdmitted(route *routev1.Route, routerName string) bool {
+	for _, ingress := range currentIngresses(route, routerName) {
+		if condition := admittedCondition(ingress); condition != nil && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// AdmittedHosts returns the distinct hosts route was admitted with by any router, sorted.
+func AdmittedHosts(route *routev1.Route) []string {
+	seen := map[string]bool{}
+	var hosts []string
+	for _, ingress := range route.Status.Ingress {
+		condition := admittedCondition(ingress)
+		if condition == nil || condition.Status != corev1.ConditionTrue || len(ingress.Host) == 0 || seen[ingress.Host] {
+			continue
+		}
+		seen[ingress.Host] = true
+		hosts = append(hosts, ingress.Host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// RejectionReasons returns, for every router that rejected the current host of route, the
+// reason it reported, or its message if it gave no reason.
+func RejectionReasons(route *routev1.Route) map[string]string {
+	reasons := map[string]string{}
+	for _, ingress := range currentIngresses(route, "") {
+		condition := admittedCondition(ingress)
+		if condition == nil || condition.Status != corev1.ConditionFalse {
+			continue
+		}
+		reason := condition.Reason
+		if len(reason) == 0 {
+			reason = condition.Message
+		}
+		reasons[ingress.RouterName] = reason
+	}
+	return reasons
+}
+
+// WaitForAdmission watches the named route until the named router, or any router when
+// routerName is empty, admits or rejects it. A *RejectedError is returned if it is rejected.
+// When routerName is empty, the first decision of any router wins, admissions taking precedence
+// over rejections observed at the same time.
+func WaitForAdmission(ctx context.Context, client typedroutev1.RoutesGetter, namespace, name, routerName string) (*routev1.Route, error) {
+	routes := client.Routes(namespace)
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return routes.List(ctx, options)
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return routes.Watch(ctx, options)
+		},
+	}
+	exists := func(store cache.Store) (bool, error) {
+		if _, ok, err := store.GetByKey(namespace + "/" + name); err != nil || !ok {
+			return false, errors.NewNotFound(routev1.Resource("route"), name)
+		}
+		return false, nil
+	}
+
+	var route *routev1.Route
+	_, err := watchtools.UntilWithSync(ctx, lw, &routev1.Route{}, exists, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("route %s/%s was deleted before it was admitted", namespace, name)
+		}
+		observed, ok := event.Object.(*routev1.Route)
+		if !ok {
+			return false, fmt.Errorf("unexpected object %T in route watch", event.Object)
+		}
+		route = observed
+		return decided(route, routerName)
+	})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return route, err
+}
+
+// decided returns true once the route was admitted, or rejected, by the routers of interest,
+// with a *RejectedError in the latter case.
+func decided(route *routev1.Route, routerName string) (bool, error) {
+	if IsAdmitted(route, routerName) {
+		return true, nil
+	}
+	for _, ingress := range currentIngresses(route, routerName) {
+		condition := admittedCondition(ingress)
+		if condition == nil || condition.Status != corev1.ConditionFalse {
+			continue
+		}
+		return true, &RejectedError{
+			Namespace:  route.Namespace,
+			Name:       route.Name,
+			RouterName: ingress.RouterName,
+			Reason:     condition.Reason,
+			Message:    condition.Message,
+		}
+	}
+	return false, nil
+}
+
+// currentIngresses returns the ingress entries of the named router, or all routers, that refer to
+// the current host of route. Entries for a previous host are stale until the router catches up.
+func currentIngresses(route *routev1.Route, routerName string) []routev1.RouteIngress {
+	var ingresses []routev1.RouteIngress
+	for _, ingress := range route.Status.Ingress {
+		if len(routerName) > 0 && ingress.RouterName != routerName {
+			continue
+		}
+		if len(route.Spec.Host) > 0 && ingress.Host != route.Spec.Host {
+			continue
+		}
+		ingresses = append(ingresses, ingress)
+	}
+	return ingresses
+}
+
+func admittedCondition(ingress routev1.RouteIngress) *routev1.RouteIngressCondition {
+	for i := range ingress.Conditions {
+		if ingress.Conditions[i].Type == routev1.RouteAdmitted {
+			return &ingress.Conditions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package admission
+
+import (
+	context "context"
+	errors "errors"
+	reflect "reflect"
+	strings "strings"
+	testing "testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+	fake "github.com/openshift/client-go/route/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	wait "k8s.io/apimachinery/pkg/util/wait"
+	watch "k8s.io/apimachinery/pkg/watch"
+	clientfeatures "k8s.io/client-go/features"
+	clientfeaturestesting "k8s.io/client-go/features/testing"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func ingress(router, host string, status corev1.ConditionStatus, reason string) routev1.RouteIngress {
+	return routev1.RouteIngress{
+		RouterName: router,
+		Host:       host,
+		Conditions: []routev1.RouteIngressCondition{{Type: routev1.RouteAdmitted, Status: status, Reason: reason}},
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		host         string
+		ingresses    []routev1.RouteIngress
+		router       string
+		wantAdmitted bool
+		wantHosts    []string
+		wantReasons  map[string]string
+	}{
+		{
+			name:        "no status",
+			host:        "a.example.com",
+			wantReasons: map[string]string{},
+		},
+		{
+			name:         "admitted by the router of interest",
+			host:         "a.example.com",
+			ingresses:    []routev1.RouteIngress{ingress("default", "a.example.com", corev1.ConditionTrue, "")},
+			router:       "default",
+			wantAdmitted: true,
+			wantHosts:    []string{"a.example.com"},
+			wantReasons:  map[string]string{},
+		},
+		{
+			name: "admitted by another router only",
+			host: "a.example.com",
+			ingresses: []routev1.RouteIngress{
+				ingress("internal", "a.example.com", corev1.ConditionTrue, ""),
+				ingress("default", "a.example.com", corev1.ConditionFalse, "HostAlreadyClaimed"),
+			},
+			router:      "default",
+			wantHosts:   []string{"a.example.com"},
+			wantReasons: map[string]string{"default": "HostAlreadyClaimed"},
+		},
+		{
+			name:        "admission of a previous host is stale",
+			host:        "b.example.com",
+			ingresses:   []routev1.RouteIngress{ingress("default", "a.example.com", corev1.ConditionTrue, "")},
+			wantHosts:   []string{"a.example.com"},
+			wantReasons: map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route := &routev1.Route{Spec: routev1.RouteSpec{Host: test.host}, Status: routev1.RouteStatus{Ingress: test.ingresses}}
+			if got := IsAdmitted(route, test.router); got != test.wantAdmitted {
+				t.Errorf("IsAdmitted: expected %t, got %t", test.wantAdmitted, got)
+			}
+			if got := AdmittedHosts(route); !reflect.DeepEqual(got, test.wantHosts) {
+				t.Errorf("AdmittedHosts: expected %v, got %v", test.wantHosts, got)
+			}
+			if got := RejectionReasons(route); !reflect.DeepEqual(got, test.wantReasons) {
+				t.Errorf("RejectionReasons: expected %v, got %v", test.wantReasons, got)
+			}
+		})
+	}
+}
+
+func TestWaitForAdmission(t *testing.T) {
+	// The reflector of UntilWithSync cannot stream the initial list from typed fake clients.
+	clientfeaturestesting.SetFeatureDuringTest(t, clientfeatures.WatchListClient, false)
+
+	pending := &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "web", ResourceVersion: "1"},
+		Spec:       routev1.RouteSpec{Host: "web.example.com"},
+	}
+	withStatus := func(ingresses ...routev1.RouteIngress) *routev1.Route {
+		route := pending.DeepCopy()
+		route.ResourceVersion = "2"
+		route.Status.Ingress = ingresses
+		return route
+	}
+
+	tests := []struct {
+		name     string
+		router   string
+		event    watch.Event
+		wantErr  func(error) bool
+		wantDone bool
+	}{
+		{
+			name:     "admitted",
+			router:   "default",
+			event:    watch.Event{Type: watch.Modified, Object: withStatus(ingress("default", "web.example.com", corev1.ConditionTrue, ""))},
+			wantDone: true,
+		},
+		{
+			name:   "rejected",
+			router: "default",
+			event:  watch.Event{Type: watch.Modified, Object: withStatus(ingress("default", "web.example.com", corev1.ConditionFalse, "HostAlreadyClaimed"))},
+			wantErr: func(err error) bool {
+				var rejected *RejectedError
+				return errors.As(err, &rejected) && rejected.Reason == "HostAlreadyClaimed" && rejected.RouterName == "default"
+			},
+			wantDone: true,
+		},
+		{
+			name:     "admitted by any router",
+			event:    watch.Event{Type: watch.Modified, Object: withStatus(ingress("internal", "web.example.com", corev1.ConditionTrue, ""))},
+			wantDone: true,
+		},
+		{
+			name:   "deleted",
+			router: "default",
+			event:  watch.Event{Type: watch.Deleted, Object: pending},
+			// The deletion may be observed before the precondition of the wait runs.
+			wantErr: func(err error) bool {
+				return apierrors.IsNotFound(err) || err != nil && strings.Contains(err.Error(), "was deleted")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(pending)
+			watcher := watch.NewFake()
+			client.PrependWatchReactor("routes", func(clienttesting.Action) (bool, watch.Interface, error) {
+				return true, watcher, nil
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+			defer cancel()
+
+			done := make(chan error, 1)
+			var route *routev1.Route
+			go func() {
+				var err error
+				route, err = WaitForAdmission(ctx, client.RouteV1(), "ns", "web", test.router)
+				done <- err
+			}()
+			watcher.Action(test.event.Type, test.event.Object)
+
+			err := <-done
+			switch {
+			case test.wantErr == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.wantErr != nil && !test.wantErr(err):
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.wantDone && (route == nil || route.ResourceVersion != "2") {
+				t.Errorf("expected the decided route, got %#v", route)
+			}
+		})
+	}
+}
+
+func TestWaitForAdmissionMissing(t *testing.T) {
+	clientfeaturestesting.SetFeatureDuringTest(t, clientfeatures.WatchListClient, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+	defer cancel()
+	if _, err := WaitForAdmission(ctx, fake.NewSimpleClientset().RouteV1(), "ns", "web", ""); !apierrors.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
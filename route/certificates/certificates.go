@@ -0,0 +1,307 @@
+// Package certificates inspects the certificates embedded in routes, reporting expiry and
+// misconfigurations such as keys that do not match or hosts that are not covered.
+package certificates
+
+import (
+	sha256 "crypto/sha256"
+	tls "crypto/tls"
+	x509 "crypto/x509"
+	hex "encoding/hex"
+	pem "encoding/pem"
+	fmt "fmt"
+	math "math"
+	sort "sort"
+	strings "strings"
+	time "time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	listersroutev1 "github.com/openshift/client-go/route/listers/route/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+)
+
+// Field names a certificate field of routev1.TLSConfig.
+type Field string
+
+// Fields of routev1.TLSConfig holding certificates or keys.
+const (
+	FieldCertificate              Field = "certificate"
+	FieldKey                      Field = "key"
+	FieldCACertificate            Field = "caCertificate"
+	FieldDestinationCACertificate Field = "destinationCACertificate"
+)
+
+// Reason classifies a problem found in the TLS configuration of a route.
+type Reason string
+
+const (
+	// ReasonInvalidPEM means a field does not hold valid PEM encoded certificates or key.
+	ReasonInvalidPEM Reason = "InvalidPEM"
+	// ReasonMissingCertificate means a key is set without a certificate, or the reverse.
+	ReasonMissingCertificate Reason = "MissingCertificate"
+	// ReasonKeyMismatch means the key does not belong to the serving certificate.
+	ReasonKeyMismatch Reason = "KeyMismatch"
+	// ReasonIncompleteChain means the serving certificate does not verify against the CA
+	// certificates of the route or the system roots.
+	ReasonIncompleteChain Reason = "IncompleteChain"
+	// ReasonHostNotCovered means the serving certificate is not valid for the host of the route.
+	ReasonHostNotCovered Reason = "HostNotCovered"
+	// ReasonExpired means a certificate is past its expiry.
+	ReasonExpired Reason = "Expired"
+	// ReasonExpiringSoon means a certificate expires within the warning threshold.
+	ReasonExpiringSoon Reason = "ExpiringSoon"
+	// ReasonNotYetValid means a certificate is not valid yet.
+	ReasonNotYetValid Reason = "NotYetValid"
+)
+
+// Problem is a misconfiguration of the TLS configuration of a route.
+type Problem struct {
+	Field   Field
+	Reason  Reason
+	Message string
+}
+
+// Certificate describes one certificate found in a route.
+type Certificate struct {
+	Field   Field
+	Subject string
+	// Fingerprint is the hex encoded SHA-256 digest of the DER encoding of the certificate.
+	Fingerprint string
+	Issuer      string
+	DNSNames    []string
+	NotBefore   time.Time
+	NotAfter    time.Time
+	// DaysToExpiry is the number of whole days until NotAfter, negative once expired.
+	DaysToExpiry int
+}
+
+// Report is the result of inspecting the TLS configuration of a route.
+type Report struct {
+	Namespace   string
+	Name        string
+	Host        string
+	Termination routev1.TLSTerminationType
+	// External is true if the serving certificate is read from a secret, which is not inspected.
+	External     bool
+	Certificates []Certificate
+	Problems     []Problem
+}
+
+// Options configures an Inspector.
+type Options struct {
+	// WarningThreshold is how long before expiry a certificate is reported as ExpiringSoon.
+	WarningThreshold time.Duration
+	// Roots are the trusted roots serving certificates are verified against, in addition to
+	// the self-signed certificates of the route. The system roots are used when nil.
+	Roots *x509.CertPool
+}
+
+// DefaultOptions returns options warning 30 days before expiry and trusting the system roots.
+func DefaultOptions() Options {
+	return Options{WarningThreshold: 30 * 24 * time.Hour}
+}
+
+// Inspector inspects the routes of a route cache.
+type Inspector struct {
+	lister  listersroutev1.RouteLister
+	options Options
+	now     func() time.Time
+}
+
+// NewInspector returns an Inspector reading routes from lister.
+func NewInspector(lister listersroutev1.RouteLister, options Options) *Inspector {
+	if options.Roots == nil {
+		if roots, err := x509.SystemCertPool(); err == nil {
+			options.Roots = roots
+		} else {
+			options.Roots = x509.NewCertPool()
+		}
+	}
+	return &Inspector{lister: lister, options: options, now: time.Now}
+}
+
+// Inspect returns the reports of the routes with a TLS configuration in namespace, or all
+// namespaces when empty, ordered by namespace and name.
+func (i *Inspector) Inspect(namespace string) ([]Report, error) {
+	var routes []*routev1.Route
+	var err error
+	if len(namespace) > 0 {
+		routes, err = i.lister.Routes(namespace).List(labels.Everything())
+	} else {
+		routes, err = i.lister.List(labels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reports []Report
+	for _, route := range routes {
+		if route.Spec.TLS == nil {
+			continue
+		}
+		reports = append(reports, i.InspectRoute(route))
+	}
+	sort.Slice(reports, func(a, b int) bool {
+		if reports[a].Namespace != reports[b].Namespace {
+			return reports[a].Namespace < reports[b].Namespace
+		}
+		return reports[a].Name < reports[b].Name
+	})
+	return reports, nil
+}
+
+// InspectRoute returns the report of the TLS configuration of route.
+func (i *Inspector) InspectRoute(route *routev1.Route) Report {
+	report := Report{Namespace: route.Namespace, Name: route.Name, Host: route.Spec.Host}
+	config := route.Spec.TLS
+	if config == nil {
+		return report
+	}
+	report.Termination = config.Termination
+	report.External = config.ExternalCertificate != nil
+
+	chain := i.parse(&report, FieldCertificate, config.Certificate)
+	cas := i.parse(&report, FieldCACertificate, config.CACertificate)
+	i.parse(&report, FieldDestinationCACertificate, config.DestinationCACertificate)
+
+	switch {
+	case len(config.Certificate) > 0 && len(config.Key) == 0:
+		report.problem(FieldKey, ReasonMissingCertificate, "a certificate is set without a key")
+	case len(config.Certificate) == 0 && len(config.Key) > 0:
+		report.problem(FieldCertificate, ReasonMissingCertificate, "a key is set without a certificate")
+	case len(config.Certificate) > 0 && len(config.Key) > 0:
+		if _, err := tls.X509KeyPair([]byte(config.Certificate), []byte(config.Key)); err != nil {
+			report.problem(FieldKey, ReasonKeyMismatch, err.Error())
+		}
+	}
+
+	if len(chain) > 0 {
+		i.verifyChain(&report, chain, cas)
+		verifyHost(&report, route, chain[0])
+	}
+	return report
+}
+
+// parse decodes the certificates of a field, recording them and their validity problems.
+func (i *Inspector) parse(report *Report, field Field, data string) []*x509.Certificate {
+	if len(strings.TrimSpace(data)) == 0 {
+		return nil
+	}
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			report.problem(field, ReasonInvalidPEM, err.Error())
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		report.problem(field, ReasonInvalidPEM, "no PEM encoded certificate found")
+		return nil
+	}
+
+	now := i.now()
+	for _, cert := range certs {
+		days := int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24))
+		report.Certificates = append(report.Certificates, Certificate{
+			Field:        field,
+			Subject:      cert.Subject.String(),
+			Fingerprint:  fingerprint(cert),
+			Issuer:       cert.Issuer.String(),
+			DNSNames:     cert.DNSNames,
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+			DaysToExpiry: days,
+		})
+		switch {
+		case now.After(cert.NotAfter):
+			report.problem(field, ReasonExpired, fmt.Sprintf("certificate %q expired on %s", cert.Subject, cert.NotAfter.Format(time.RFC3339)))
+		case now.Before(cert.NotBefore):
+			report.problem(field, ReasonNotYetValid, fmt.Sprintf("certificate %q is not valid before %s", cert.Subject, cert.NotBefore.Format(time.RFC3339)))
+		case cert.NotAfter.Sub(now) < i.options.WarningThreshold:
+			report.problem(field, ReasonExpiringSoon, fmt.Sprintf("certificate %q expires in %d days", cert.Subject, days))
+		}
+	}
+	return certs
+}
+
+// verifyChain verifies the serving certificate against the rest of its chain and the CA
+// certificates of the route, trusting their self-signed certificates and the configured roots.
+func (i *Inspector) verifyChain(report *Report, chain, cas []*x509.Certificate) {
+	roots := i.options.Roots.Clone()
+	intermediates := x509.NewCertPool()
+	for _, cert := range append(append([]*x509.Certificate{}, chain[1:]...), cas...) {
+		if isSelfSigned(cert) {
+			roots.AddCert(cert)
+		} else {
+			intermediates.AddCert(cert)
+		}
+	}
+	leaf := chain[0]
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		// expiry is reported separately, only the chain itself is verified here
+		CurrentTime: leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) / 2),
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		report.problem(FieldCertificate, ReasonIncompleteChain, err.Error())
+	}
+}
+
+// verifyHost checks that leaf covers the host of route, and every subdomain of its parent
+// domain for routes with the Subdomain wildcard policy.
+func verifyHost(report *Report, route *routev1.Route, leaf *x509.Certificate) {
+	host := route.Spec.Host
+	if len(host) == 0 {
+		return
+	}
+	if err := leaf.VerifyHostname(host); err != nil {
+		report.problem(FieldCertificate, ReasonHostNotCovered, err.Error())
+		return
+	}
+	if route.Spec.WildcardPolicy != routev1.WildcardPolicySubdomain {
+		return
+	}
+	if _, parent, ok := strings.Cut(host, "."); ok {
+		wildcard := "*." + parent
+		for _, name := range leaf.DNSNames {
+			if strings.EqualFold(name, wildcard) {
+				return
+			}
+		}
+		report.problem(FieldCertificate, ReasonHostNotCovered, fmt.Sprintf("certificate does not cover the wildcard %s", wildcard))
+	}
+}
+
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return cert.CheckSignatureFrom(cert) == nil
+}
+
+func (r *Report) problem(field Field, reason Reason, message string) {
+	r.Problems = append(r.Problems, Problem{Field: field, Reason: reason, Message: message})
+}
+
+// HasProblem returns true if the report contains a problem with the given reason.
+func (r *Report) HasProblem(reason Reason) bool {
+	for _, problem := range r.Problems {
+		if problem.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package certificates
+
+import (
+	prometheus "github.com/prometheus/client_golang/prometheus"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+const (
+	namespace = "openshift"
+	subsystem = "route"
+)
+
+// Collector is a prometheus.Collector reporting the expiry of the certificates of routes and the
+// problems found in their TLS configuration, computed from an Inspector at every scrape.
+type Collector struct {
+	inspector *Inspector
+
+	expiry   *prometheus.Desc
+	problems *prometheus.Desc
+}
+
+var _ prometheus.Collector = &Collector{}
+
+// NewCollector returns a Collector reporting on the routes of inspector. It must be registered
+// with a prometheus.Registerer.
+func NewCollector(inspector *Inspector) *Collector {
+	return &Collector{
+		inspector: inspector,
+		expiry: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "certificate_expiry_timestamp_seconds"),
+			"Expiry time of the certificates embedded in routes",
+			[]string{"namespace", "route", "field", "subject", "fingerprint"},
+			nil,
+		),
+		problems: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "tls_problems"),
+			"Counts the problems found in the TLS configuration of routes by reason",
+			[]string{"namespace", "route", "reason"},
+			nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.expiry
+	ch <- c.problems
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	reports, err := c.inspector.Inspect("")
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, report := range reports {
+		seen := map[[2]string]bool{}
+		for _, cert := range report.Certificates {
+			// the same certificate repeated in a field would produce duplicate series, while
+			// distinct certificates sharing a subject, such as a renewed CA, are all reported
+			key := [2]string{string(cert.Field), cert.Fingerprint}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			ch <- prometheus.MustNewConstMetric(c.expiry, prometheus.GaugeValue, float64(cert.NotAfter.Unix()),
+				report.Namespace, report.Name, string(cert.Field), cert.Subject, cert.Fingerprint)
+		}
+		counts := map[Reason]int{}
+		for _, problem := range report.Problems {
+			counts[problem.Reason]++
+		}
+		for reason, count := range counts {
+			ch <- prometheus.MustNewConstMetric(c.problems, prometheus.GaugeValue, float64(count), report.Namespace, report.Name, string(reason))
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package certificates
+
+import (
+	ecdsa "crypto/ecdsa"
+	elliptic "crypto/elliptic"
+	crand "crypto/rand"
+	x509 "crypto/x509"
+	pkix "crypto/x509/pkix"
+	pem "encoding/pem"
+	big "math/big"
+	strings "strings"
+	testing "testing"
+	time "time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	listersroutev1 "github.com/openshift/client-go/route/listers/route/v1"
+	prometheus "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// selfSigned returns a PEM encoded CA certificate for subject, valid until notAfter.
+func selfSigned(t *testing.T, subject string, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(notAfter.Unix()),
+		Subject:               pkix.Name{CommonName: subject},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestCollector(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	current := selfSigned(t, "route-ca", now.Add(90*24*time.Hour))
+	renewed := selfSigned(t, "route-ca", now.Add(400*24*time.Hour))
+
+	routes := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, name := range []string{"a", "b"} {
+		route := &routev1.Route{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+			Spec: routev1.RouteSpec{TLS: &routev1.TLSConfig{
+				Termination: routev1.TLSTerminationReencrypt,
+				// the current CA is repeated, and shares its subject with the renewed one
+				DestinationCACertificate: current + renewed + current,
+			}},
+		}
+		if err := routes.Add(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+	collector := NewCollector(NewInspector(listersroutev1.NewRouteLister(routes), DefaultOptions()))
+
+	// a registry rejects duplicate series when gathering
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+	if _, err := registry.Gather(); err != nil {
+		t.Fatal(err)
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+	expiries := map[string]float64{}
+	for metric := range ch {
+		if !strings.Contains(metric.Desc().String(), "certificate_expiry_timestamp_seconds") {
+			continue
+		}
+		m := &dto.Metric{}
+		if err := metric.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		var route, fingerprint string
+		for _, label := range m.GetLabel() {
+			switch label.GetName() {
+			case "route":
+				route = label.GetValue()
+			case "fingerprint":
+				fingerprint = label.GetValue()
+			}
+		}
+		expiries[route+"/"+fingerprint[:8]] = m.GetGauge().GetValue()
+	}
+	if len(expiries) != 4 {
+		t.Fatalf("expected both certificates of both routes, got %v", expiries)
+	}
+	distinct := map[float64]int{}
+	for _, expiry := range expiries {
+		distinct[expiry]++
+	}
+	if distinct[float64(now.Add(90*24*time.Hour).Unix())] != 2 || distinct[float64(now.Add(400*24*time.Hour).Unix())] != 2 {
+		t.Errorf("unexpected expiries %v", expiries)
+	}
+}
@@ -0,0 +1,322 @@
+// Package conflicts predicts which routes a router admits when several routes claim the same
+// host and path, reporting the routes that are shadowed. A router shard only sees the routes its
+// selectors match, so conflicts are predicted per shard when Options.Shard is set.
+package conflicts
+
+import (
+	fmt "fmt"
+	sort "sort"
+	strings "strings"
+
+	routev1 "github.com/openshift/api/route/v1"
+	listersroutev1 "github.com/openshift/client-go/route/listers/route/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+)
+
+// Reason explains why a route is shadowed.
+type Reason string
+
+const (
+	// ReasonHostAlreadyClaimed means an older route in another namespace owns the host, or the
+	// wildcard domain of the host.
+	ReasonHostAlreadyClaimed Reason = "HostAlreadyClaimed"
+	// ReasonPathAlreadyClaimed means an older route claims the same host and path.
+	ReasonPathAlreadyClaimed Reason = "PathAlreadyClaimed"
+)
+
+// Options configures an Analyzer.
+type Options struct {
+	// AllowCrossNamespaceClaims disables the namespace ownership check, as routers started with
+	// ROUTER_DISABLE_NAMESPACE_OWNERSHIP_CHECK do. Routes in different namespaces can then share a
+	// host as long as their paths differ.
+	AllowCrossNamespaceClaims bool
+	// Shard restricts the analysis to the routes of one router shard, all routes when nil.
+	Shard *Shard
+}
+
+// Shard selects the routes served by a router shard, like the ROUTE_LABELS and NAMESPACE_LABELS
+// of the router. Routes outside the shard are neither admitted nor shadow other routes.
+type Shard struct {
+	// RouteSelector selects routes by their labels, every route when nil.
+	RouteSelector labels.Selector
+	// NamespaceSelector selects routes by the labels of their namespace, every namespace when
+	// nil. Namespaces must be set with it.
+	NamespaceSelector labels.Selector
+	// Namespaces is the namespace cache NamespaceSelector is evaluated against.
+	Namespaces listerscorev1.NamespaceLister
+}
+
+// selects returns true if the shard serves route. Routes in unknown namespaces are not served.
+func (s *Shard) selects(route *routev1.Route) (bool, error) {
+	if s == nil {
+		return true, nil
+	}
+	if s.RouteSelector != nil && !s.RouteSelector.Matches(labels.Set(route.Labels)) {
+		return false, nil
+	}
+	if s.NamespaceSelector == nil {
+		return true, nil
+	}
+	if s.Namespaces == nil {
+		return false, fmt.Errorf("a namespace selector requires a namespace lister")
+	}
+	namespace, err := s.Namespaces.Get(route.Namespace)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return s.NamespaceSelector.Matches(labels.Set(namespace.Labels)), nil
+}
+
+// Key identifies the claim of a route: its host, or wildcard domain for Subdomain routes, and path.
+type Key struct {
+	Host           string
+	Path           string
+	WildcardPolicy routev1.WildcardPolicyType
+}
+
+// Shadowed is a route that the router rejects in favour of an older one.
+type Shadowed struct {
+	Route *routev1.Route
+	// By is the route that wins the claim.
+	By     *routev1.Route
+	Reason Reason
+}
+
+// Group is the set of routes claiming the same Key.
+type Group struct {
+	Key Key
+	// Winner is the route the router admits for the key, nil if every route of the group is
+	// shadowed by the ownership of another namespace.
+	Winner   *routev1.Route
+	Shadowed []Shadowed
+}
+
+// Analyzer predicts route admission from a route cache.
+type Analyzer struct {
+	lister  listersroutev1.RouteLister
+	options Options
+}
+
+// NewAnalyzer returns an Analyzer reading routes from lister.
+func NewAnalyzer(lister listersroutev1.RouteLister, options Options) *Analyzer {
+	return &Analyzer{lister: lister, options: options}
+}
+
+// Groups returns every claimed key with its winner and shadowed routes, ordered by host and path.
+func (a *Analyzer) Groups() ([]Group, error) {
+	routes, err := a.routes()
+	if err != nil {
+		return nil, err
+	}
+	claims := a.admit(routes)
+
+	var groups []Group
+	for _, group := range claims.groups {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Key.Host != groups[j].Key.Host {
+			return groups[i].Key.Host < groups[j].Key.Host
+		}
+		if groups[i].Key.Path != groups[j].Key.Path {
+			return groups[i].Key.Path < groups[j].Key.Path
+		}
+		return groups[i].Key.WildcardPolicy < groups[j].Key.WildcardPolicy
+	})
+	return groups, nil
+}
+
+// Conflicts returns the groups with at least one shadowed route.
+func (a *Analyzer) Conflicts() ([]Group, error) {
+	groups, err := a.Groups()
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []Group
+	for _, group := range groups {
+		if len(group.Shadowed) > 0 {
+			conflicts = append(conflicts, group)
+		}
+	}
+	return conflicts, nil
+}
+
+// Check predicts whether route, which is about to be created, would be shadowed by an existing
+// route. It returns nil if the route would be admitted, or is outside the shard. Any existing
+// route with the same namespace and name is ignored, so updates can be checked too.
+func (a *Analyzer) Check(route *routev1.Route) (*Shadowed, error) {
+	if ok, err := a.options.Shard.selects(route); err != nil || !ok {
+		return nil, err
+	}
+	routes, err := a.routes()
+	if err != nil {
+		return nil, err
+	}
+	var others []*routev1.Route
+	for _, existing := range routes {
+		if existing.Namespace != route.Namespace || existing.Name != route.Name {
+			others = append(others, existing)
+		}
+	}
+	claims := a.admit(others)
+	// a route that is being created is younger than every existing one
+	return claims.claim(route), nil
+}
+
+// routes returns the routes served by the shard.
+func (a *Analyzer) routes() ([]*routev1.Route, error) {
+	routes, err := a.lister.List(labels.Everything())
+	if err != nil || a.options.Shard == nil {
+		return routes, err
+	}
+	var served []*routev1.Route
+	for _, route := range routes {
+		ok, err := a.options.Shard.selects(route)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			served = append(served, route)
+		}
+	}
+	return served, nil
+}
+
+// admit replays the admission of routes by age, oldest first, like the router does.
+func (a *Analyzer) admit(routes []*routev1.Route) *claims {
+	sorted := append([]*routev1.Route{}, routes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return older(sorted[i], sorted[j])
+	})
+	c := &claims{
+		crossNamespace: a.options.AllowCrossNamespaceClaims,
+		hostOwners:     map[string]*routev1.Route{},
+		domainOwners:   map[string]*routev1.Route{},
+		winners:        map[Key]*routev1.Route{},
+		groups:         map[Key]*Group{},
+	}
+	for _, route := range sorted {
+		key, ok := keyFor(route)
+		if !ok {
+			continue
+		}
+		group, ok := c.groups[key]
+		if !ok {
+			group = &Group{Key: key}
+			c.groups[key] = group
+		}
+		if shadowed := c.claim(route); shadowed != nil {
+			group.Shadowed = append(group.Shadowed, *shadowed)
+			continue
+		}
+		group.Winner = route
+		c.record(route, key)
+	}
+	return c
+}
+
+// claims is the state of the router after admitting a set of routes.
+type claims struct {
+	crossNamespace bool
+	// hostOwners maps hosts to the oldest admitted route for them.
+	hostOwners map[string]*routev1.Route
+	// domainOwners maps wildcard domains to the oldest admitted Subdomain route for them.
+	domainOwners map[string]*routev1.Route
+	winners      map[Key]*routev1.Route
+	groups       map[Key]*Group
+}
+
+// claim returns how route is shadowed by the admitted routes, nil if it would be admitted.
+func (c *claims) claim(route *routev1.Route) *Shadowed {
+	key, ok := keyFor(route)
+	if !ok {
+		return nil
+	}
+	if !c.crossNamespace {
+		if owner := c.owner(route); owner != nil {
+			return &Shadowed{Route: route, By: owner, Reason: ReasonHostAlreadyClaimed}
+		}
+	}
+	if winner, ok := c.winners[key]; ok {
+		return &Shadowed{Route: route, By: winner, Reason: ReasonPathAlreadyClaimed}
+	}
+	return nil
+}
+
+// owner returns an admitted route of another namespace owning the host of route or its domain.
+func (c *claims) owner(route *routev1.Route) *routev1.Route {
+	host := route.Spec.Host
+	domain := wildcardDomain(host)
+	if owner, ok := c.hostOwners[host]; ok && owner.Namespace != route.Namespace {
+		return owner
+	}
+	if owner, ok := c.domainOwners[domain]; ok && owner.Namespace != route.Namespace {
+		return owner
+	}
+	if route.Spec.WildcardPolicy == routev1.WildcardPolicySubdomain {
+		// a wildcard route cannot take over a domain in which another namespace owns hosts
+		for claimed, owner := range c.hostOwners {
+			if owner.Namespace != route.Namespace && wildcardDomain(claimed) == domain {
+				return owner
+			}
+		}
+	}
+	return nil
+}
+
+func (c *claims) record(route *routev1.Route, key Key) {
+	c.winners[key] = route
+	if _, ok := c.hostOwners[route.Spec.Host]; !ok {
+		c.hostOwners[route.Spec.Host] = route
+	}
+	if route.Spec.WildcardPolicy == routev1.WildcardPolicySubdomain {
+		if _, ok := c.domainOwners[key.Host]; !ok {
+			c.domainOwners[key.Host] = route
+		}
+	}
+}
+
+// keyFor returns the claim of route, false for routes without a host.
+func keyFor(route *routev1.Route) (Key, bool) {
+	if len(route.Spec.Host) == 0 {
+		return Key{}, false
+	}
+	policy := route.Spec.WildcardPolicy
+	if len(policy) == 0 {
+		policy = routev1.WildcardPolicyNone
+	}
+	key := Key{Host: route.Spec.Host, Path: route.Spec.Path, WildcardPolicy: policy}
+	if policy == routev1.WildcardPolicySubdomain {
+		key.Host = wildcardDomain(route.Spec.Host)
+	}
+	return key, true
+}
+
+// wildcardDomain returns the wildcard domain covering host, such as *.example.com for
+// www.example.com.
+func wildcardDomain(host string) string {
+	if _, parent, ok := strings.Cut(host, "."); ok {
+		return "*." + parent
+	}
+	return host
+}
+
+// older returns true if a is admitted before b: the older route wins, ties are broken by UID as
+// the router does, and then by namespace and name for stability.
+func older(a, b *routev1.Route) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.UID != b.UID {
+		return a.UID < b.UID
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
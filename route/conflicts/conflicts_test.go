@@ -0,0 +1,300 @@
+package conflicts
+
+import (
+	reflect "reflect"
+	sort "sort"
+	testing "testing"
+	time "time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	listersroutev1 "github.com/openshift/client-go/route/listers/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// testRoute returns a route created age minutes after a fixed time.
+func testRoute(namespace, name, host, path string, policy routev1.WildcardPolicyType, age int) *routev1.Route {
+	return &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Date(2024, 1, 1, 0, age, 0, 0, time.UTC)),
+		},
+		Spec: routev1.RouteSpec{Host: host, Path: path, WildcardPolicy: policy},
+	}
+}
+
+func testLister(t *testing.T, routes ...*routev1.Route) listersroutev1.RouteLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, route := range routes {
+		if err := indexer.Add(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return listersroutev1.NewRouteLister(indexer)
+}
+
+// shadowing describes a Shadowed as <namespace>/<name> by <namespace>/<name>: <reason>.
+func shadowing(shadowed Shadowed) string {
+	return shadowed.Route.Namespace + "/" + shadowed.Route.Name + " by " + shadowed.By.Namespace + "/" + shadowed.By.Name + ": " + string(shadowed.Reason)
+}
+
+func TestConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		routes  []*routev1.Route
+		want    []string
+	}{
+		{
+			name: "distinct hosts",
+			routes: []*routev1.Route{
+				testRoute("a", "one", "one.example.com", "", "", 0),
+				testRoute("b", "two", "two.example.com", "", "", 1),
+			},
+		},
+		{
+			name: "same host and path in one namespace",
+			routes: []*routev1.Route{
+				testRoute("a", "new", "www.example.com", "/", "", 2),
+				testRoute("a", "old", "www.example.com", "/", "", 1),
+			},
+			want: []string{"a/new by a/old: PathAlreadyClaimed"},
+		},
+		{
+			name: "different paths in one namespace",
+			routes: []*routev1.Route{
+				testRoute("a", "root", "www.example.com", "", "", 0),
+				testRoute("a", "api", "www.example.com", "/api", "", 1),
+			},
+		},
+		{
+			name: "host owned by another namespace",
+			routes: []*routev1.Route{
+				testRoute("a", "root", "www.example.com", "", "", 0),
+				testRoute("b", "api", "www.example.com", "/api", "", 1),
+			},
+			want: []string{"b/api by a/root: HostAlreadyClaimed"},
+		},
+		{
+			name:    "different paths across namespaces without the ownership check",
+			options: Options{AllowCrossNamespaceClaims: true},
+			routes: []*routev1.Route{
+				testRoute("a", "root", "www.example.com", "", "", 0),
+				testRoute("b", "api", "www.example.com", "/api", "", 1),
+			},
+		},
+		{
+			name:    "same path across namespaces without the ownership check",
+			options: Options{AllowCrossNamespaceClaims: true},
+			routes: []*routev1.Route{
+				testRoute("a", "old", "www.example.com", "/api", "", 0),
+				testRoute("b", "new", "www.example.com", "/api", "", 1),
+			},
+			want: []string{"b/new by a/old: PathAlreadyClaimed"},
+		},
+		{
+			name: "wildcard domain owned by another namespace",
+			routes: []*routev1.Route{
+				testRoute("a", "wildcard", "wild.example.com", "", routev1.WildcardPolicySubdomain, 0),
+				testRoute("b", "host", "www.example.com", "", "", 1),
+			},
+			want: []string{"b/host by a/wildcard: HostAlreadyClaimed"},
+		},
+		{
+			name: "wildcard cannot take over a domain with hosts of another namespace",
+			routes: []*routev1.Route{
+				testRoute("a", "host", "www.example.com", "", "", 0),
+				testRoute("b", "wildcard", "wild.example.com", "", routev1.WildcardPolicySubdomain, 1),
+			},
+			want: []string{"b/wildcard by a/host: HostAlreadyClaimed"},
+		},
+		{
+			name: "wildcard and hosts in one namespace",
+			routes: []*routev1.Route{
+				testRoute("a", "wildcard", "wild.example.com", "", routev1.WildcardPolicySubdomain, 0),
+				testRoute("a", "host", "www.example.com", "", "", 1),
+			},
+		},
+		{
+			name: "wildcards of the same domain",
+			routes: []*routev1.Route{
+				testRoute("a", "old", "one.example.com", "", routev1.WildcardPolicySubdomain, 0),
+				testRoute("a", "new", "two.example.com", "", routev1.WildcardPolicySubdomain, 1),
+			},
+			want: []string{"a/new by a/old: PathAlreadyClaimed"},
+		},
+		{
+			name: "wildcard of a parent domain does not own nested hosts",
+			routes: []*routev1.Route{
+				testRoute("a", "wildcard", "wild.example.com", "", routev1.WildcardPolicySubdomain, 0),
+				testRoute("b", "nested", "www.apps.example.com", "", "", 1),
+			},
+		},
+		{
+			name: "routes without a host are ignored",
+			routes: []*routev1.Route{
+				testRoute("a", "one", "", "", "", 0),
+				testRoute("b", "two", "", "", "", 1),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conflicts, err := NewAnalyzer(testLister(t, test.routes...), test.options).Conflicts()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, group := range conflicts {
+				for _, shadowed := range group.Shadowed {
+					got = append(got, shadowing(shadowed))
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestOlderBreaksTiesByUID(t *testing.T) {
+	a := testRoute("b", "a", "www.example.com", "", "", 0)
+	a.UID = "2"
+	b := testRoute("a", "b", "www.example.com", "", "", 0)
+	b.UID = "1"
+	conflicts, err := NewAnalyzer(testLister(t, a, b), Options{}).Conflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Winner != b {
+		t.Fatalf("expected a/b to win, got %#v", conflicts)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	existing := []*routev1.Route{
+		testRoute("a", "root", "www.example.com", "", "", 0),
+		testRoute("a", "api", "www.example.com", "/api", "", 1),
+	}
+	tests := []struct {
+		name  string
+		route *routev1.Route
+		want  string
+	}{
+		{
+			name:  "new path in the owning namespace",
+			route: testRoute("a", "docs", "www.example.com", "/docs", "", 10),
+		},
+		{
+			name:  "claimed path",
+			route: testRoute("a", "api2", "www.example.com", "/api", "", 10),
+			want:  "a/api2 by a/api: PathAlreadyClaimed",
+		},
+		{
+			name:  "host owned by another namespace",
+			route: testRoute("b", "docs", "www.example.com", "/docs", "", 10),
+			want:  "b/docs by a/root: HostAlreadyClaimed",
+		},
+		{
+			name:  "update of an existing route",
+			route: testRoute("a", "api", "www.example.com", "/api", "", 1),
+		},
+	}
+	analyzer := NewAnalyzer(testLister(t, existing...), Options{})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shadowed, err := analyzer.Check(test.route)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if shadowed != nil {
+				got = shadowing(*shadowed)
+			}
+			if got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestShard(t *testing.T) {
+	labeled := func(route *routev1.Route, key, value string) *routev1.Route {
+		route.Labels = map[string]string{key: value}
+		return route
+	}
+	routes := []*routev1.Route{
+		labeled(testRoute("internal", "old", "www.example.com", "", "", 0), "type", "internal"),
+		labeled(testRoute("public", "new", "www.example.com", "", "", 1), "type", "public"),
+		labeled(testRoute("public", "newer", "www.example.com", "", "", 2), "type", "public"),
+	}
+	namespaces := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for name, tier := range map[string]string{"internal": "private", "public": "edge"} {
+		if err := namespaces.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"tier": tier}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		shard *Shard
+		want  []string
+	}{
+		{
+			name: "every route",
+			want: []string{"public/new by internal/old: HostAlreadyClaimed", "public/newer by internal/old: HostAlreadyClaimed"},
+		},
+		{
+			name:  "route selector",
+			shard: &Shard{RouteSelector: labels.SelectorFromSet(labels.Set{"type": "public"})},
+			want:  []string{"public/newer by public/new: PathAlreadyClaimed"},
+		},
+		{
+			name:  "namespace selector",
+			shard: &Shard{NamespaceSelector: labels.SelectorFromSet(labels.Set{"tier": "edge"}), Namespaces: listerscorev1.NewNamespaceLister(namespaces)},
+			want:  []string{"public/newer by public/new: PathAlreadyClaimed"},
+		},
+		{
+			name: "both selectors",
+			shard: &Shard{
+				RouteSelector:     labels.SelectorFromSet(labels.Set{"type": "internal"}),
+				NamespaceSelector: labels.SelectorFromSet(labels.Set{"tier": "edge"}),
+				Namespaces:        listerscorev1.NewNamespaceLister(namespaces),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conflicts, err := NewAnalyzer(testLister(t, routes...), Options{Shard: test.shard}).Conflicts()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, group := range conflicts {
+				for _, shadowed := range group.Shadowed {
+					got = append(got, shadowing(shadowed))
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+
+	// a route the shard does not serve is never shadowed by it
+	analyzer := NewAnalyzer(testLister(t, routes...), Options{Shard: &Shard{RouteSelector: labels.SelectorFromSet(labels.Set{"type": "internal"})}})
+	if shadowed, err := analyzer.Check(testRoute("public", "docs", "www.example.com", "", "", 10)); err != nil || shadowed != nil {
+		t.Errorf("expected a route outside the shard to be ignored, got %v, %v", shadowed, err)
+	}
+	if shadowed, err := analyzer.Check(labeled(testRoute("public", "docs", "www.example.com", "", "", 10), "type", "internal")); err != nil || shadowed == nil {
+		t.Errorf("expected a route of the shard to be shadowed, got %v, %v", shadowed, err)
+	}
+	if _, err := NewAnalyzer(testLister(t, routes...), Options{Shard: &Shard{NamespaceSelector: labels.Everything()}}).Groups(); err == nil {
+		t.Errorf("expected a namespace selector without namespaces to be rejected")
+	}
+}
@@ -0,0 +1,485 @@
+// Package ingress converts between networking.k8s.io/v1 Ingresses and routes, reporting the parts
+// of either that the other cannot express.
+package ingress
+
+import (
+	fmt "fmt"
+	fnv "hash/fnv"
+	sort "sort"
+	strings "strings"
+
+	routev1 "github.com/openshift/api/route/v1"
+	applyroutev1 "github.com/openshift/client-go/route/applyconfigurations/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
+	field "k8s.io/apimachinery/pkg/util/validation/field"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	// TerminationAnnotation selects the TLS termination of the routes generated for an Ingress:
+	// edge, reencrypt or passthrough. Routes are edge terminated when it is not set.
+	TerminationAnnotation = "route.openshift.io/termination"
+	// DestinationCACertificateSecretAnnotation names the secret whose tls.crt is used as the
+	// destination CA certificate of reencrypt routes generated for an Ingress.
+	DestinationCACertificateSecretAnnotation = "route.openshift.io/destination-ca-certificate-secret"
+)
+
+// Loss is a part of the source object that the conversion dropped or changed in meaning.
+type Loss struct {
+	Field   string
+	Message string
+}
+
+func (l Loss) String() string {
+	return fmt.Sprintf("%s: %s", l.Field, l.Message)
+}
+
+// Converter converts Ingresses to routes and back.
+type Converter struct {
+	services listerscorev1.ServiceLister
+	secrets  listerscorev1.SecretLister
+}
+
+// NewConverter returns a Converter. services is used to translate between service port numbers,
+// which Ingresses use, and the target ports of routes; it may be nil, in which case numeric ports
+// are copied as is and reported as lossy. secrets is used to copy the certificates of Ingress TLS
+// secrets into routes, as the ingress-to-route controller does; it may be nil, in which case the
+// secrets are referenced as external certificates and reported as lossy.
+func NewConverter(services listerscorev1.ServiceLister, secrets listerscorev1.SecretLister) *Converter {
+	return &Converter{services: services, secrets: secrets}
+}
+
+// ToRoutes returns a route for every host and path of ingress, named after the ingress and a hash
+// of the host and path so that the names are stable across conversions. Paths that a route cannot
+// tell apart, such as "/" and "", or any path of a passthrough host, become a single route for the
+// first of them.
+func (c *Converter) ToRoutes(ingress *networkingv1.Ingress) ([]*applyroutev1.RouteApplyConfiguration, []Loss, error) {
+	var losses []Loss
+	lose := func(path *field.Path, format string, args ...interface{}) {
+		losses = append(losses, Loss{Field: path.String(), Message: fmt.Sprintf(format, args...)})
+	}
+	spec := field.NewPath("spec")
+	if ingress.Spec.IngressClassName != nil {
+		lose(spec.Child("ingressClassName"), "routes are admitted by router shards, not ingress classes")
+	}
+	if ingress.Spec.DefaultBackend != nil {
+		lose(spec.Child("defaultBackend"), "routes require a host, the default backend is dropped")
+	}
+
+	termination := routev1.TLSTerminationEdge
+	if value, ok := ingress.Annotations[TerminationAnnotation]; ok {
+		switch t := routev1.TLSTerminationType(value); t {
+		case routev1.TLSTerminationEdge, routev1.TLSTerminationReencrypt, routev1.TLSTerminationPassthrough:
+			termination = t
+		default:
+			return nil, nil, fmt.Errorf("invalid %s annotation %q", TerminationAnnotation, value)
+		}
+	}
+	certificates := map[string]*certificate{}
+	for i, tls := range ingress.Spec.TLS {
+		tlsPath := spec.Child("tls").Index(i)
+		if len(tls.Hosts) == 0 {
+			lose(tlsPath.Child("hosts"), "a TLS entry without hosts cannot be mapped to a route")
+			continue
+		}
+		cert := &certificate{}
+		if termination != routev1.TLSTerminationPassthrough && len(tls.SecretName) > 0 {
+			var err error
+			if cert, err = c.certificateFor(ingress.Namespace, tls.SecretName, tlsPath.Child("secretName"), lose); err != nil {
+				return nil, nil, err
+			}
+		}
+		for _, host := range tls.Hosts {
+			certificates[host] = cert
+		}
+	}
+	var destinationCA string
+	if termination == routev1.TLSTerminationReencrypt && len(certificates) > 0 {
+		var err error
+		if destinationCA, err = c.destinationCAFor(ingress, lose); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var routes []*applyroutev1.RouteApplyConfiguration
+	// emitted maps the host and effective path of every route to the ingress path it was built from
+	emitted := map[[2]string]*field.Path{}
+	for i, rule := range ingress.Spec.Rules {
+		rulePath := spec.Child("rules").Index(i)
+		if len(rule.Host) == 0 {
+			lose(rulePath.Child("host"), "routes require a host, the rule is dropped")
+			continue
+		}
+		if strings.HasPrefix(rule.Host, "*.") {
+			lose(rulePath.Child("host"), "wildcard hosts require a route with the Subdomain wildcard policy and an explicit host, the rule is dropped")
+			continue
+		}
+		if rule.HTTP == nil {
+			lose(rulePath.Child("http"), "the rule has no paths and is dropped")
+			continue
+		}
+		for j, path := range rule.HTTP.Paths {
+			pathPath := rulePath.Child("http", "paths").Index(j)
+			if path.Backend.Service == nil {
+				lose(pathPath.Child("backend"), "routes can only target services, the path is dropped")
+				continue
+			}
+			if path.PathType != nil && *path.PathType == networkingv1.PathTypeExact {
+				lose(pathPath.Child("pathType"), "routes match paths by prefix, the exact match becomes a prefix match")
+			}
+			routePath := path.Path
+			if routePath == "/" {
+				routePath = ""
+			}
+
+			cert, secure := certificates[rule.Host]
+			if secure && termination == routev1.TLSTerminationPassthrough && len(routePath) > 0 {
+				lose(pathPath.Child("path"), "passthrough routes cannot match paths, the path is dropped")
+				routePath = ""
+			}
+			key := [2]string{rule.Host, routePath}
+			if first, ok := emitted[key]; ok {
+				lose(pathPath, "the path matches the same requests as %s, the path is dropped", first)
+				continue
+			}
+			emitted[key] = pathPath
+
+			routeSpec := applyroutev1.RouteSpec().
+				WithHost(rule.Host).
+				WithTo(applyroutev1.RouteTargetReference().
+					WithKind("Service").
+					WithName(path.Backend.Service.Name).
+					WithWeight(100))
+			if len(routePath) > 0 {
+				routeSpec.WithPath(routePath)
+			}
+			port, err := c.targetPort(ingress.Namespace, path.Backend.Service)
+			switch {
+			case err != nil:
+				return nil, nil, err
+			case port == nil:
+				lose(pathPath.Child("backend", "service", "port", "number"), "the service port could not be resolved and is used as the target port")
+				routeSpec.WithPort(applyroutev1.RoutePort().WithTargetPort(intstr.FromInt32(path.Backend.Service.Port.Number)))
+			default:
+				routeSpec.WithPort(applyroutev1.RoutePort().WithTargetPort(*port))
+			}
+
+			if secure {
+				tls := applyroutev1.TLSConfig().WithTermination(termination)
+				if termination != routev1.TLSTerminationPassthrough {
+					// without a certificate the default certificate of the router is served
+					switch {
+					case len(cert.certificate) > 0:
+						tls.WithCertificate(cert.certificate).WithKey(cert.key)
+					case len(cert.external) > 0:
+						tls.WithExternalCertificate(applyroutev1.LocalObjectReference().WithName(cert.external))
+					}
+					if len(destinationCA) > 0 {
+						tls.WithDestinationCACertificate(destinationCA)
+					}
+					tls.WithInsecureEdgeTerminationPolicy(routev1.InsecureEdgeTerminationPolicyRedirect)
+				}
+				routeSpec.WithTLS(tls)
+			}
+
+			route := applyroutev1.Route(routeName(ingress.Name, rule.Host, routePath), ingress.Namespace).
+				WithLabels(ingress.Labels).
+				WithAnnotations(copyAnnotations(ingress.Annotations)).
+				WithSpec(routeSpec)
+			routes = append(routes, route)
+		}
+	}
+	for host := range certificates {
+		if !hasRule(ingress, host) {
+			lose(spec.Child("tls"), "host %s has a TLS entry but no rule", host)
+		}
+	}
+	return routes, losses, nil
+}
+
+// certificate is the serving certificate of the routes of a host, either copied from a TLS secret
+// or referencing it as an external certificate. Both are empty when the router default certificate
+// is served.
+type certificate struct {
+	certificate string
+	key         string
+	external    string
+}
+
+// certificateFor returns the serving certificate copied from the named TLS secret. Without a
+// secret lister the secret is referenced as an external certificate instead.
+func (c *Converter) certificateFor(namespace, secretName string, path *field.Path, lose func(*field.Path, string, ...interface{})) (*certificate, error) {
+	if c.secrets == nil {
+		lose(path, "secret %s is referenced as an external certificate, which requires the RouteExternalCertificate feature gate and a router allowed to read the secret; without them the route has no certificate of its own", secretName)
+		return &certificate{external: secretName}, nil
+	}
+	secret, err := c.secrets.Secrets(namespace).Get(secretName)
+	if errors.IsNotFound(err) {
+		lose(path, "secret %s does not exist, the router default certificate is served", secretName)
+		return &certificate{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cert, key := secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+	if secret.Type != corev1.SecretTypeTLS || len(cert) == 0 || len(key) == 0 {
+		lose(path, "secret %s is not a TLS secret with a certificate and key, the router default certificate is served", secretName)
+		return &certificate{}, nil
+	}
+	// the certificate is copied, later changes of the secret are not reflected in the routes
+	return &certificate{certificate: string(cert), key: string(key)}, nil
+}
+
+// destinationCAFor returns the destination CA certificate of the reencrypt routes of ingress, read
+// from the secret named by DestinationCACertificateSecretAnnotation.
+func (c *Converter) destinationCAFor(ingress *networkingv1.Ingress, lose func(*field.Path, string, ...interface{})) (string, error) {
+	path := field.NewPath("metadata", "annotations").Key(DestinationCACertificateSecretAnnotation)
+	secretName, ok := ingress.Annotations[DestinationCACertificateSecretAnnotation]
+	switch {
+	case !ok:
+		lose(path, "reencrypt routes get no destination CA certificate and only trust backends serving certificates signed by the service CA")
+		return "", nil
+	case c.secrets == nil:
+		lose(path, "secret %s cannot be read without a secret lister, reencrypt routes get no destination CA certificate", secretName)
+		return "", nil
+	}
+	secret, err := c.secrets.Secrets(ingress.Namespace).Get(secretName)
+	if errors.IsNotFound(err) {
+		lose(path, "secret %s does not exist, reencrypt routes get no destination CA certificate", secretName)
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	ca := secret.Data[corev1.TLSCertKey]
+	if len(ca) == 0 {
+		lose(path, "secret %s has no %s, reencrypt routes get no destination CA certificate", secretName, corev1.TLSCertKey)
+		return "", nil
+	}
+	return string(ca), nil
+}
+
+// targetPort translates the service port of backend into the target port of a route, nil if a
+// numeric port cannot be resolved through the service.
+func (c *Converter) targetPort(namespace string, backend *networkingv1.IngressServiceBackend) (*intstr.IntOrString, error) {
+	if len(backend.Port.Name) > 0 {
+		port := intstr.FromString(backend.Port.Name)
+		return &port, nil
+	}
+	if c.services == nil {
+		return nil, nil
+	}
+	service, err := c.services.Services(namespace).Get(backend.Name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, port := range service.Spec.Ports {
+		if port.Port != backend.Port.Number {
+			continue
+		}
+		// routes look named ports up among the service ports, unnamed ones by target port
+		if len(port.Name) > 0 {
+			target := intstr.FromString(port.Name)
+			return &target, nil
+		}
+		target := port.TargetPort
+		if target.Type == intstr.Int && target.IntVal == 0 {
+			target = intstr.FromInt32(port.Port)
+		}
+		return &target, nil
+	}
+	return nil, nil
+}
+
+// ToIngress returns an Ingress named name with a rule for every route, which must all be in the
+// same namespace.
+func (c *Converter) ToIngress(name string, routes ...*routev1.Route) (*networkingv1.Ingress, []Loss, error) {
+	if len(routes) == 0 {
+		return nil, nil, fmt.Errorf("no routes to convert")
+	}
+	namespace := routes[0].Namespace
+	ingress := &networkingv1.Ingress{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+
+	var losses []Loss
+	terminations := map[routev1.TLSTerminationType]bool{}
+	secrets := map[string][]string{}
+	rules := map[string]*networkingv1.IngressRule{}
+	var hosts []string
+	for _, route := range routes {
+		if route.Namespace != namespace {
+			return nil, nil, fmt.Errorf("route %s/%s is not in namespace %s", route.Namespace, route.Name, namespace)
+		}
+		routeName := route.Namespace + "/" + route.Name
+		lose := func(path *field.Path, format string, args ...interface{}) {
+			losses = append(losses, Loss{Field: routeName + ": " + path.String(), Message: fmt.Sprintf(format, args...)})
+		}
+		spec := field.NewPath("spec")
+		if len(route.Spec.Host) == 0 {
+			lose(spec.Child("host"), "routes without an explicit host cannot be converted, the route is dropped")
+			continue
+		}
+		if route.Spec.To.Kind != "" && route.Spec.To.Kind != "Service" {
+			lose(spec.Child("to", "kind"), "only service backends can be converted, the route is dropped")
+			continue
+		}
+		if len(route.Spec.AlternateBackends) > 0 {
+			lose(spec.Child("alternateBackends"), "ingresses cannot split traffic, only the primary backend is kept")
+		}
+		if len(route.Spec.Subdomain) > 0 {
+			lose(spec.Child("subdomain"), "the subdomain is dropped in favour of the host")
+		}
+		if route.Spec.HTTPHeaders != nil {
+			lose(spec.Child("httpHeaders"), "header actions are dropped")
+		}
+
+		host := route.Spec.Host
+		if route.Spec.WildcardPolicy == routev1.WildcardPolicySubdomain {
+			if _, parent, ok := strings.Cut(host, "."); ok {
+				host = "*." + parent
+			}
+		}
+
+		backend := &networkingv1.IngressServiceBackend{Name: route.Spec.To.Name}
+		if route.Spec.Port != nil {
+			port := route.Spec.Port.TargetPort
+			if port.Type == intstr.String {
+				backend.Port.Name = port.StrVal
+			} else {
+				number, ok, err := c.servicePort(namespace, route.Spec.To.Name, port.IntVal)
+				if err != nil {
+					return nil, nil, err
+				}
+				if !ok {
+					lose(spec.Child("port", "targetPort"), "the target port could not be resolved and is used as the service port")
+				}
+				backend.Port.Number = number
+			}
+		} else {
+			lose(spec.Child("port"), "routes without a port target every service port, ingresses need one; set the port of the backend")
+		}
+
+		path := route.Spec.Path
+		if len(path) == 0 {
+			path = "/"
+		}
+		pathType := networkingv1.PathTypePrefix
+		rule, ok := rules[host]
+		if !ok {
+			rule = &networkingv1.IngressRule{Host: host, IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{}}}
+			rules[host] = rule
+			hosts = append(hosts, host)
+		}
+		rule.HTTP.Paths = append(rule.HTTP.Paths, networkingv1.HTTPIngressPath{
+			Path:     path,
+			PathType: &pathType,
+			Backend:  networkingv1.IngressBackend{Service: backend},
+		})
+
+		if tls := route.Spec.TLS; tls != nil {
+			tlsPath := spec.Child("tls")
+			terminations[tls.Termination] = true
+			switch {
+			case tls.ExternalCertificate != nil:
+				secrets[tls.ExternalCertificate.Name] = append(secrets[tls.ExternalCertificate.Name], host)
+			case len(tls.Certificate) > 0:
+				lose(tlsPath.Child("certificate"), "ingresses read certificates from secrets, create a TLS secret and reference it")
+			default:
+				// the default certificate of the ingress controller is used
+				secrets[""] = append(secrets[""], host)
+			}
+			if len(tls.DestinationCACertificate) > 0 {
+				lose(tlsPath.Child("destinationCACertificate"), "the destination CA certificate is dropped")
+			}
+			if tls.InsecureEdgeTerminationPolicy == routev1.InsecureEdgeTerminationPolicyAllow {
+				lose(tlsPath.Child("insecureEdgeTerminationPolicy"), "allowing insecure traffic depends on the ingress controller")
+			}
+		}
+	}
+
+	for _, host := range hosts {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, *rules[host])
+	}
+	secretNames := make([]string, 0, len(secrets))
+	for secret := range secrets {
+		secretNames = append(secretNames, secret)
+	}
+	sort.Strings(secretNames)
+	for _, secret := range secretNames {
+		ingress.Spec.TLS = append(ingress.Spec.TLS, networkingv1.IngressTLS{Hosts: secrets[secret], SecretName: secret})
+	}
+
+	if len(terminations) > 1 {
+		losses = append(losses, Loss{Field: "spec.tls", Message: "the routes use different TLS terminations, which a single ingress cannot express; edge termination is assumed"})
+	} else {
+		for termination := range terminations {
+			if termination != routev1.TLSTerminationEdge {
+				ingress.Annotations = map[string]string{TerminationAnnotation: string(termination)}
+				losses = append(losses, Loss{Field: "metadata.annotations", Message: fmt.Sprintf("%s termination is only honored by OpenShift", termination)})
+			}
+		}
+	}
+	return ingress, losses, nil
+}
+
+// servicePort translates the target port of a route into a service port number.
+func (c *Converter) servicePort(namespace, serviceName string, targetPort int32) (int32, bool, error) {
+	if c.services == nil {
+		return targetPort, false, nil
+	}
+	service, err := c.services.Services(namespace).Get(serviceName)
+	if errors.IsNotFound(err) {
+		return targetPort, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	for _, port := range service.Spec.Ports {
+		target := port.TargetPort
+		if (target.Type == intstr.Int && target.IntVal == targetPort) || (target.Type == intstr.Int && target.IntVal == 0 && port.Port == targetPort) {
+			return port.Port, true, nil
+		}
+	}
+	return targetPort, false, nil
+}
+
+// routeName returns a stable name for the route of host and path generated for an ingress.
+func routeName(ingressName, host, path string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(host + path))
+	suffix := fmt.Sprintf("-%08x", hash.Sum32())
+	if max := 253 - len(suffix); len(ingressName) > max {
+		ingressName = ingressName[:max]
+	}
+	return ingressName + suffix
+}
+
+// copyAnnotations returns the annotations to copy from an ingress to its routes.
+func copyAnnotations(annotations map[string]string) map[string]string {
+	copied := map[string]string{}
+	for key, value := range annotations {
+		if key == TerminationAnnotation || key == DestinationCACertificateSecretAnnotation || key == "kubectl.kubernetes.io/last-applied-configuration" {
+			continue
+		}
+		copied[key] = value
+	}
+	return copied
+}
+
+func hasRule(ingress *networkingv1.Ingress, host string) bool {
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host == host {
+			return true
+		}
+	}
+	return false
+}
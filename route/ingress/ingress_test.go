@@ -0,0 +1,237 @@
+package ingress
+
+import (
+	fmt "fmt"
+	reflect "reflect"
+	strings "strings"
+	testing "testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+	applyroutev1 "github.com/openshift/client-go/route/applyconfigurations/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
+	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// services serves web, whose port 80 targets 8080, and api, whose port 80 is named http.
+func services(t *testing.T) listerscorev1.ServiceLister {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, service := range []*corev1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "web"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt32(8080)}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "api"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt32(9090)}}},
+		},
+	} {
+		if err := indexer.Add(service); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return listerscorev1.NewServiceLister(indexer)
+}
+
+// rule returns a rule for host whose paths are given as "path service".
+func rule(host string, paths ...string) networkingv1.IngressRule {
+	prefix := networkingv1.PathTypePrefix
+	value := &networkingv1.HTTPIngressRuleValue{}
+	for _, p := range paths {
+		path, service, _ := strings.Cut(p, " ")
+		value.Paths = append(value.Paths, networkingv1.HTTPIngressPath{
+			Path:     path,
+			PathType: &prefix,
+			Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+				Name: service,
+				Port: networkingv1.ServiceBackendPort{Number: 80},
+			}},
+		})
+	}
+	return networkingv1.IngressRule{Host: host, IngressRuleValue: networkingv1.IngressRuleValue{HTTP: value}}
+}
+
+// renderRoutes renders routes as "host path -> service:port termination", failing on routes
+// sharing a name.
+func renderRoutes(t *testing.T, routes []*applyroutev1.RouteApplyConfiguration) []string {
+	t.Helper()
+	names := map[string]bool{}
+	var rendered []string
+	for _, route := range routes {
+		if names[*route.GetName()] {
+			t.Errorf("route name %s is used twice", *route.GetName())
+		}
+		names[*route.GetName()] = true
+		spec := route.Spec
+		path, termination := "", "insecure"
+		if spec.Path != nil {
+			path = *spec.Path
+		}
+		if spec.TLS != nil {
+			termination = string(*spec.TLS.Termination)
+		}
+		rendered = append(rendered, fmt.Sprintf("%s %s -> %s:%s %s", *spec.Host, path, *spec.To.Name, spec.Port.TargetPort.String(), termination))
+	}
+	return rendered
+}
+
+func renderLosses(losses []Loss) []string {
+	var rendered []string
+	for _, loss := range losses {
+		rendered = append(rendered, loss.String())
+	}
+	return rendered
+}
+
+func TestToRoutes(t *testing.T) {
+	tests := []struct {
+		name       string
+		ingress    networkingv1.Ingress
+		wantRoutes []string
+		wantLosses []string
+	}{
+		{
+			name: "paths",
+			ingress: networkingv1.Ingress{Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{
+				rule("a.example.com", "/ web", " api", "/api api"),
+				rule("a.example.com", "/api web"),
+				rule("", "/ web"),
+				rule("*.example.com", "/ web"),
+			}}},
+			wantRoutes: []string{
+				"a.example.com  -> web:8080 insecure",
+				"a.example.com /api -> api:http insecure",
+			},
+			wantLosses: []string{
+				"spec.rules[0].http.paths[1]: the path matches the same requests as spec.rules[0].http.paths[0], the path is dropped",
+				"spec.rules[1].http.paths[0]: the path matches the same requests as spec.rules[0].http.paths[2], the path is dropped",
+				"spec.rules[2].host: routes require a host, the rule is dropped",
+				"spec.rules[3].host: wildcard hosts require a route with the Subdomain wildcard policy and an explicit host, the rule is dropped",
+			},
+		},
+		{
+			name: "passthrough",
+			ingress: networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{TerminationAnnotation: "passthrough"}},
+				Spec: networkingv1.IngressSpec{
+					TLS: []networkingv1.IngressTLS{{Hosts: []string{"secure.example.com"}}},
+					Rules: []networkingv1.IngressRule{
+						rule("secure.example.com", "/ web", "/admin api"),
+						rule("plain.example.com", "/ web", "/admin api"),
+					},
+				},
+			},
+			wantRoutes: []string{
+				"secure.example.com  -> web:8080 passthrough",
+				"plain.example.com  -> web:8080 insecure",
+				"plain.example.com /admin -> api:http insecure",
+			},
+			wantLosses: []string{
+				"spec.rules[0].http.paths[1].path: passthrough routes cannot match paths, the path is dropped",
+				"spec.rules[0].http.paths[1]: the path matches the same requests as spec.rules[0].http.paths[0], the path is dropped",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.ingress.Namespace, test.ingress.Name = "ns", "app"
+			routes, losses, err := NewConverter(services(t), nil).ToRoutes(&test.ingress)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := renderRoutes(t, routes); !reflect.DeepEqual(got, test.wantRoutes) {
+				t.Errorf("expected routes\n%s\ngot\n%s", strings.Join(test.wantRoutes, "\n"), strings.Join(got, "\n"))
+			}
+			if got := renderLosses(losses); !reflect.DeepEqual(got, test.wantLosses) {
+				t.Errorf("expected losses\n%s\ngot\n%s", strings.Join(test.wantLosses, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+
+	// the names only depend on the ingress, host and path
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+		Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{rule("a.example.com", "/ web")}},
+	}
+	first, _, _ := NewConverter(nil, nil).ToRoutes(ingress)
+	ingress.Spec.Rules = []networkingv1.IngressRule{rule("b.example.com", "/ web"), rule("a.example.com", " api")}
+	second, _, _ := NewConverter(nil, nil).ToRoutes(ingress)
+	if *first[0].GetName() != *second[1].GetName() || *first[0].GetName() == *second[0].GetName() {
+		t.Errorf("expected the name of a.example.com to be stable, got %s and %s", *first[0].GetName(), *second[1].GetName())
+	}
+}
+
+func TestToIngress(t *testing.T) {
+	route := func(name, host, path, service string, port *intstr.IntOrString, tls *routev1.TLSConfig) *routev1.Route {
+		route := &routev1.Route{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+			Spec:       routev1.RouteSpec{Host: host, Path: path, To: routev1.RouteTargetReference{Kind: "Service", Name: service}, TLS: tls},
+		}
+		if port != nil {
+			route.Spec.Port = &routev1.RoutePort{TargetPort: *port}
+		}
+		return route
+	}
+	http, unknown := intstr.FromString("http"), intstr.FromInt32(7070)
+	target := intstr.FromInt32(8080)
+
+	ingress, losses, err := NewConverter(services(t), nil).ToIngress("app",
+		route("web", "a.example.com", "", "web", &target, &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge}),
+		route("api", "a.example.com", "/api", "api", &http, nil),
+		route("any", "b.example.com", "/", "api", nil, nil),
+		route("other", "c.example.com", "", "web", &unknown, nil),
+		route("generated", "", "", "web", &target, nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rules []string
+	for _, rule := range ingress.Spec.Rules {
+		for _, path := range rule.HTTP.Paths {
+			port := path.Backend.Service.Port.Name
+			if len(port) == 0 {
+				port = fmt.Sprint(path.Backend.Service.Port.Number)
+			}
+			rules = append(rules, fmt.Sprintf("%s %s -> %s:%s", rule.Host, path.Path, path.Backend.Service.Name, port))
+		}
+	}
+	if want := []string{
+		"a.example.com / -> web:80",
+		"a.example.com /api -> api:http",
+		"b.example.com / -> api:0",
+		"c.example.com / -> web:7070",
+	}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("expected rules\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(rules, "\n"))
+	}
+	if want := []networkingv1.IngressTLS{{Hosts: []string{"a.example.com"}}}; !reflect.DeepEqual(ingress.Spec.TLS, want) {
+		t.Errorf("expected the default certificate for a.example.com, got %+v", ingress.Spec.TLS)
+	}
+	if len(ingress.Annotations) != 0 {
+		t.Errorf("expected no termination annotation for edge routes, got %v", ingress.Annotations)
+	}
+	if got, want := renderLosses(losses), []string{
+		"ns/any: spec.port: routes without a port target every service port, ingresses need one; set the port of the backend",
+		"ns/other: spec.port.targetPort: the target port could not be resolved and is used as the service port",
+		"ns/generated: spec.host: routes without an explicit host cannot be converted, the route is dropped",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected losses\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	reencrypt := route("web", "a.example.com", "", "web", &target, &routev1.TLSConfig{Termination: routev1.TLSTerminationReencrypt})
+	ingress, _, err = NewConverter(nil, nil).ToIngress("app", reencrypt)
+	if err != nil || ingress.Annotations[TerminationAnnotation] != "reencrypt" {
+		t.Errorf("expected the termination to be annotated, got %v: %v", ingress.Annotations, err)
+	}
+	elsewhere := route("web", "a.example.com", "", "web", &target, nil)
+	elsewhere.Namespace = "other"
+	if _, _, err := NewConverter(nil, nil).ToIngress("app", reencrypt, elsewhere); err == nil {
+		t.Errorf("expected routes of several namespaces to be rejected")
+	}
+	if _, _, err := NewConverter(nil, nil).ToIngress("app"); err == nil {
+		t.Errorf("expected converting no routes to fail")
+	}
+}
@@ -0,0 +1,296 @@
+// Package traffic shifts the traffic of a route between weighted backends in stages, using
+// server-side apply, and rolls shifts back.
+package traffic
+
+import (
+	context "context"
+	fmt "fmt"
+	math "math"
+	time "time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	applyroutev1 "github.com/openshift/client-go/route/applyconfigurations/route/v1"
+	typedroutev1 "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	field "k8s.io/apimachinery/pkg/util/validation/field"
+	retry "k8s.io/client-go/util/retry"
+)
+
+const (
+	// MaxAlternateBackends is the maximum number of alternate backends of a route.
+	MaxAlternateBackends = 3
+	// MaxWeight is the maximum weight of a backend.
+	MaxWeight = 256
+	// DefaultFieldManager is the field manager used when Options.FieldManager is empty.
+	DefaultFieldManager = "route-traffic-shifter"
+)
+
+// Backend is a weighted backend of a route.
+type Backend struct {
+	// Kind of the backend, Service when empty.
+	Kind   string
+	Name   string
+	Weight int32
+}
+
+// Distribution is the weighted backends of a route. The first backend is the primary backend,
+// spec.to, and the others the alternate backends.
+type Distribution []Backend
+
+// FromRoute returns the current distribution of route. Backends without a weight have the default
+// weight of 100.
+func FromRoute(route *routev1.Route) Distribution {
+	distribution := Distribution{backendFor(route.Spec.To)}
+	for _, backend := range route.Spec.AlternateBackends {
+		distribution = append(distribution, backendFor(backend))
+	}
+	return distribution
+}
+
+func backendFor(ref routev1.RouteTargetReference) Backend {
+	backend := Backend{Kind: ref.Kind, Name: ref.Name, Weight: 100}
+	if len(backend.Kind) == 0 {
+		backend.Kind = "Service"
+	}
+	if ref.Weight != nil {
+		backend.Weight = *ref.Weight
+	}
+	return backend
+}
+
+// Validate checks that d can be set on a route: a primary backend and at most three alternates,
+// distinct backends, weights between 0 and 256, and at least one backend receiving traffic.
+func (d Distribution) Validate() field.ErrorList {
+	var errs field.ErrorList
+	path := field.NewPath("backends")
+	if len(d) == 0 {
+		return append(errs, field.Required(path, "a primary backend is required"))
+	}
+	if len(d) > MaxAlternateBackends+1 {
+		errs = append(errs, field.TooMany(path, len(d), MaxAlternateBackends+1))
+	}
+	seen := map[[2]string]bool{}
+	var total int32
+	for i, backend := range d {
+		backendPath := path.Index(i)
+		if len(backend.Name) == 0 {
+			errs = append(errs, field.Required(backendPath.Child("name"), ""))
+		}
+		key := [2]string{kindOf(backend), backend.Name}
+		if seen[key] {
+			errs = append(errs, field.Duplicate(backendPath, backend.Name))
+		}
+		seen[key] = true
+		if backend.Weight < 0 || backend.Weight > MaxWeight {
+			errs = append(errs, field.Invalid(backendPath.Child("weight"), backend.Weight, fmt.Sprintf("must be between 0 and %d", MaxWeight)))
+		}
+		total += backend.Weight
+	}
+	if total <= 0 {
+		errs = append(errs, field.Invalid(path, total, "at least one backend must have a positive weight"))
+	}
+	return errs
+}
+
+// Steps returns the distributions moving from one distribution to another in the given number of
+// equal steps, the last one being to. Backends missing from either side have weight zero. The
+// primary backend of from stays primary until the last step. A single step is returned when the
+// intermediate distributions would need more backends than a route allows.
+func Steps(from, to Distribution, steps int) []Distribution {
+	if steps < 1 {
+		steps = 1
+	}
+	// the union keeps the order of from, followed by the backends only in to
+	var union Distribution
+	index := map[[2]string]int{}
+	for _, backends := range []Distribution{from, to} {
+		for _, backend := range backends {
+			key := [2]string{kindOf(backend), backend.Name}
+			if _, ok := index[key]; !ok {
+				index[key] = len(union)
+				union = append(union, Backend{Kind: kindOf(backend), Name: backend.Name})
+			}
+		}
+	}
+	if len(union) > MaxAlternateBackends+1 {
+		return []Distribution{to}
+	}
+	weights := func(d Distribution) []int32 {
+		w := make([]int32, len(union))
+		for _, backend := range d {
+			w[index[[2]string{kindOf(backend), backend.Name}]] = backend.Weight
+		}
+		return w
+	}
+	fromWeights, toWeights := weights(from), weights(to)
+
+	var result []Distribution
+	for step := 1; step < steps; step++ {
+		d := make(Distribution, len(union))
+		for i, backend := range union {
+			delta := float64(toWeights[i]-fromWeights[i]) * float64(step) / float64(steps)
+			backend.Weight = fromWeights[i] + int32(math.Round(delta))
+			d[i] = backend
+		}
+		result = append(result, d)
+	}
+	return append(result, to)
+}
+
+// Options configures a shift.
+type Options struct {
+	// Steps is the number of stages of the shift, one when zero.
+	Steps int
+	// Pause is how long to wait after every stage but the last.
+	Pause time.Duration
+	// Check is called after every stage has been applied, may be nil. An error aborts the shift.
+	Check func(ctx context.Context, step int, route *routev1.Route) error
+	// RollbackOnError restores the previous distribution if the shift is aborted.
+	RollbackOnError bool
+	// FieldManager is the server-side apply field manager, DefaultFieldManager when empty.
+	FieldManager string
+}
+
+// Result describes a shift.
+type Result struct {
+	// Route is the last applied state of the route.
+	Route *routev1.Route
+	// Previous is the distribution before the shift, to pass to Rollback.
+	Previous Distribution
+	// Applied is the number of stages that were applied.
+	Applied int
+}
+
+// Shifter shifts the traffic of routes.
+type Shifter struct {
+	client typedroutev1.RoutesGetter
+}
+
+// NewShifter returns a Shifter using client.
+func NewShifter(client typedroutev1.RoutesGetter) *Shifter {
+	return &Shifter{client: client}
+}
+
+// Shift moves the traffic of the named route to target in stages. The result is returned even
+// when the shift fails, so that the previous distribution is always available for Rollback.
+func (s *Shifter) Shift(ctx context.Context, namespace, name string, target Distribution, options Options) (*Result, error) {
+	if errs := target.Validate(); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+	route, err := s.client.Routes(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Route: route, Previous: FromRoute(route)}
+
+	steps := Steps(result.Previous, target, options.Steps)
+	for i, step := range steps {
+		route, err := s.apply(ctx, route, step, options.FieldManager)
+		if err == nil && options.Check != nil {
+			err = options.Check(ctx, i+1, route)
+		}
+		if err == nil && i < len(steps)-1 && options.Pause > 0 {
+			err = sleep(ctx, options.Pause)
+		}
+		if route != nil {
+			result.Route = route
+			result.Applied = i + 1
+		}
+		if err != nil {
+			err = fmt.Errorf("traffic shift of route %s/%s failed at step %d of %d: %w", namespace, name, i+1, len(steps), err)
+			if options.RollbackOnError && result.Applied > 0 {
+				if restored, rollbackErr := s.Rollback(context.WithoutCancel(ctx), namespace, name, result.Previous, options.FieldManager); rollbackErr != nil {
+					err = fmt.Errorf("%w; rollback failed: %v", err, rollbackErr)
+				} else {
+					result.Route = restored
+				}
+			}
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// Rollback restores the distribution of the named route to previous in a single step.
+func (s *Shifter) Rollback(ctx context.Context, namespace, name string, previous Distribution, fieldManager string) (*routev1.Route, error) {
+	if errs := previous.Validate(); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+	route, err := s.client.Routes(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, route, previous, fieldManager)
+}
+
+// apply sets distribution on route with server-side apply. Alternate backends co-owned by other
+// field managers survive an apply that omits them, so they are then removed with an update.
+func (s *Shifter) apply(ctx context.Context, route *routev1.Route, distribution Distribution, fieldManager string) (*routev1.Route, error) {
+	if errs := distribution.Validate(); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+	if len(fieldManager) == 0 {
+		fieldManager = DefaultFieldManager
+	}
+	routes := s.client.Routes(route.Namespace)
+
+	primary := distribution[0]
+	spec := applyroutev1.RouteSpec().WithTo(targetFor(primary))
+	for _, backend := range distribution[1:] {
+		spec.WithAlternateBackends(targetFor(backend))
+	}
+	applied, err := routes.Apply(ctx, applyroutev1.Route(route.Name, route.Namespace).WithSpec(spec), metav1.ApplyOptions{FieldManager: fieldManager, Force: true})
+	if err != nil {
+		return nil, err
+	}
+	if len(applied.Spec.AlternateBackends) == len(distribution)-1 {
+		return applied, nil
+	}
+
+	var updated *routev1.Route
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := routes.Get(ctx, route.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		wanted := map[[2]string]bool{}
+		for _, backend := range distribution[1:] {
+			wanted[[2]string{kindOf(backend), backend.Name}] = true
+		}
+		var backends []routev1.RouteTargetReference
+		for _, backend := range current.Spec.AlternateBackends {
+			if wanted[[2]string{kindOf(backendFor(backend)), backend.Name}] {
+				backends = append(backends, backend)
+			}
+		}
+		current.Spec.AlternateBackends = backends
+		updated, err = routes.Update(ctx, current, metav1.UpdateOptions{FieldManager: fieldManager})
+		return err
+	})
+	return updated, err
+}
+
+func targetFor(backend Backend) *applyroutev1.RouteTargetReferenceApplyConfiguration {
+	return applyroutev1.RouteTargetReference().
+		WithKind(kindOf(backend)).
+		WithName(backend.Name).
+		WithWeight(backend.Weight)
+}
+
+func kindOf(backend Backend) string {
+	if len(backend.Kind) == 0 {
+		return "Service"
+	}
+	return backend.Kind
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
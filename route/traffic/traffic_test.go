@@ -0,0 +1,243 @@
+package traffic
+
+import (
+	context "context"
+	errors "errors"
+	reflect "reflect"
+	testing "testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+	fake "github.com/openshift/client-go/route/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		distribution Distribution
+		wantErrs     int
+	}{
+		{
+			name:         "primary only",
+			distribution: Distribution{{Name: "a", Weight: 100}},
+		},
+		{
+			name:         "primary and three alternates",
+			distribution: Distribution{{Name: "a", Weight: 1}, {Name: "b", Weight: 0}, {Name: "c", Weight: 256}, {Kind: "Service", Name: "d", Weight: 2}},
+		},
+		{
+			name:     "empty",
+			wantErrs: 1,
+		},
+		{
+			name:         "four alternates",
+			distribution: Distribution{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}, {Name: "c", Weight: 1}, {Name: "d", Weight: 1}, {Name: "e", Weight: 1}},
+			wantErrs:     1,
+		},
+		{
+			name:         "duplicate backend with the default kind",
+			distribution: Distribution{{Name: "a", Weight: 1}, {Kind: "Service", Name: "a", Weight: 1}},
+			wantErrs:     1,
+		},
+		{
+			name:         "weights out of range",
+			distribution: Distribution{{Name: "a", Weight: 257}, {Name: "b", Weight: -1}},
+			wantErrs:     2,
+		},
+		{
+			name:         "no traffic",
+			distribution: Distribution{{Name: "a", Weight: 0}, {Name: "b", Weight: 0}},
+			wantErrs:     1,
+		},
+		{
+			name:         "missing name",
+			distribution: Distribution{{Weight: 1}},
+			wantErrs:     1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if errs := test.distribution.Validate(); len(errs) != test.wantErrs {
+				t.Errorf("expected %d errors, got %v", test.wantErrs, errs)
+			}
+		})
+	}
+}
+
+func TestSteps(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  Distribution
+		to    Distribution
+		steps int
+		want  []Distribution
+	}{
+		{
+			name:  "single step",
+			from:  Distribution{{Name: "a", Weight: 100}},
+			to:    Distribution{{Name: "b", Weight: 100}},
+			steps: 1,
+			want:  []Distribution{{{Name: "b", Weight: 100}}},
+		},
+		{
+			name:  "non-positive steps are a single step",
+			from:  Distribution{{Name: "a", Weight: 100}},
+			to:    Distribution{{Name: "b", Weight: 100}},
+			steps: 0,
+			want:  []Distribution{{{Name: "b", Weight: 100}}},
+		},
+		{
+			name:  "new backend is ramped up while the primary is kept",
+			from:  Distribution{{Name: "a", Weight: 100}},
+			to:    Distribution{{Name: "b", Weight: 100}},
+			steps: 4,
+			want: []Distribution{
+				{{Kind: "Service", Name: "a", Weight: 75}, {Kind: "Service", Name: "b", Weight: 25}},
+				{{Kind: "Service", Name: "a", Weight: 50}, {Kind: "Service", Name: "b", Weight: 50}},
+				{{Kind: "Service", Name: "a", Weight: 25}, {Kind: "Service", Name: "b", Weight: 75}},
+				{{Name: "b", Weight: 100}},
+			},
+		},
+		{
+			name:  "weights are rounded",
+			from:  Distribution{{Name: "a", Weight: 100}, {Name: "b", Weight: 0}},
+			to:    Distribution{{Name: "a", Weight: 0}, {Name: "b", Weight: 100}},
+			steps: 3,
+			want: []Distribution{
+				{{Kind: "Service", Name: "a", Weight: 67}, {Kind: "Service", Name: "b", Weight: 33}},
+				{{Kind: "Service", Name: "a", Weight: 33}, {Kind: "Service", Name: "b", Weight: 67}},
+				{{Name: "a", Weight: 0}, {Name: "b", Weight: 100}},
+			},
+		},
+		{
+			name:  "union keeps the order of from, then the new backends",
+			from:  Distribution{{Name: "b", Weight: 10}, {Name: "a", Weight: 10}},
+			to:    Distribution{{Name: "c", Weight: 10}, {Name: "a", Weight: 30}},
+			steps: 2,
+			want: []Distribution{
+				{{Kind: "Service", Name: "b", Weight: 5}, {Kind: "Service", Name: "a", Weight: 20}, {Kind: "Service", Name: "c", Weight: 5}},
+				{{Name: "c", Weight: 10}, {Name: "a", Weight: 30}},
+			},
+		},
+		{
+			name:  "more than four backends collapse to one step",
+			from:  Distribution{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}, {Name: "c", Weight: 1}},
+			to:    Distribution{{Name: "d", Weight: 1}, {Name: "e", Weight: 1}},
+			steps: 5,
+			want:  []Distribution{{{Name: "d", Weight: 1}, {Name: "e", Weight: 1}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Steps(test.from, test.to, test.steps)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+			for i, step := range got {
+				if errs := step.Validate(); len(errs) > 0 {
+					t.Errorf("step %d is invalid: %v", i, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestFromRoute(t *testing.T) {
+	weight := int32(20)
+	route := &routev1.Route{Spec: routev1.RouteSpec{
+		To:                routev1.RouteTargetReference{Name: "a"},
+		AlternateBackends: []routev1.RouteTargetReference{{Kind: "Service", Name: "b", Weight: &weight}},
+	}}
+	want := Distribution{{Kind: "Service", Name: "a", Weight: 100}, {Kind: "Service", Name: "b", Weight: 20}}
+	if got := FromRoute(route); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestShift(t *testing.T) {
+	weight := int32(100)
+	route := &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+		Spec: routev1.RouteSpec{
+			Host:              "app.example.com",
+			To:                routev1.RouteTargetReference{Kind: "Service", Name: "blue", Weight: &weight},
+			AlternateBackends: []routev1.RouteTargetReference{{Kind: "Service", Name: "old", Weight: new(int32)}},
+		},
+	}
+	target := Distribution{{Name: "green", Weight: 100}}
+	previous := Distribution{{Kind: "Service", Name: "blue", Weight: 100}, {Kind: "Service", Name: "old", Weight: 0}}
+
+	t.Run("staged", func(t *testing.T) {
+		client := fake.NewClientset(route.DeepCopy())
+		var observed []Distribution
+		result, err := NewShifter(client.RouteV1()).Shift(context.Background(), "ns", "app", target, Options{
+			Steps: 2,
+			Check: func(_ context.Context, _ int, route *routev1.Route) error {
+				observed = append(observed, FromRoute(route))
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result.Previous, previous) {
+			t.Errorf("expected previous %v, got %v", previous, result.Previous)
+		}
+		want := []Distribution{
+			{{Kind: "Service", Name: "blue", Weight: 50}, {Kind: "Service", Name: "old", Weight: 0}, {Kind: "Service", Name: "green", Weight: 50}},
+			{{Kind: "Service", Name: "green", Weight: 100}},
+		}
+		if !reflect.DeepEqual(observed, want) {
+			t.Errorf("expected steps %v, got %v", want, observed)
+		}
+		if result.Applied != 2 || result.Route.Spec.Host != "app.example.com" {
+			t.Errorf("expected both steps applied to the route, got %d steps and %#v", result.Applied, result.Route.Spec)
+		}
+		// the initial backends are not owned by the shifter, so removing one needs an update
+		var verbs []string
+		for _, action := range client.Actions() {
+			verbs = append(verbs, action.GetVerb())
+		}
+		if want := []string{"get", "patch", "patch", "get", "update"}; !reflect.DeepEqual(verbs, want) {
+			t.Errorf("expected requests %v, got %v", want, verbs)
+		}
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		client := fake.NewClientset(route.DeepCopy())
+		failure := errors.New("error rate too high")
+		result, err := NewShifter(client.RouteV1()).Shift(context.Background(), "ns", "app", target, Options{
+			Steps: 2,
+			Check: func(_ context.Context, step int, _ *routev1.Route) error {
+				if step == 1 {
+					return failure
+				}
+				return nil
+			},
+			RollbackOnError: true,
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("expected the check error, got %v", err)
+		}
+		if result.Applied != 1 {
+			t.Errorf("expected one step applied, got %d", result.Applied)
+		}
+		current, err := client.RouteV1().Routes("ns").Get(context.Background(), "app", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := FromRoute(current); !reflect.DeepEqual(got, previous) {
+			t.Errorf("expected the previous distribution to be restored, got %v", got)
+		}
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		client := fake.NewClientset(route.DeepCopy())
+		if _, err := NewShifter(client.RouteV1()).Shift(context.Background(), "ns", "app", Distribution{{Name: "green", Weight: 0}}, Options{}); err == nil {
+			t.Fatal("expected an error")
+		}
+		if len(client.Actions()) != 0 {
+			t.Errorf("expected no requests, got %v", client.Actions())
+		}
+	})
+}